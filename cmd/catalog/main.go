@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/handler"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/images"
 	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
 	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/storage"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/cache"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/config"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/db"
-	"github.com/JuniorCrafter/fooddelivery/internal/platform/httpmw"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -26,29 +30,27 @@ func main() {
 	repository := pg.New(pool)
 	catService := service.New(repository)
 
-	r := chi.NewRouter()
+	// Кэш в Redis необязателен: без него каталог просто читает из базы
+	rdb, err := cache.NewRedisClient(cfg.RedisAddr, "")
+	if err != nil {
+		log.Printf("Redis недоступен, каталог работает без кэша: %v", err)
+	} else {
+		catService = service.NewCached(catService, cache.New(rdb, cfg.CatalogCacheTTL))
+	}
+	// Картинки товаров пока храним на локальном диске
+	store, err := storage.NewLocal(cfg.ImageDir)
+	if err != nil {
+		log.Fatalf("Не удалось подготовить хранилище картинок: %v", err)
+	}
+	uploader := images.New(store, catService, cfg.MaxImageSize)
+
+	catHandler := handler.New(catService, uploader, store)
 
-	// Открытая ручка: список товаров могут смотреть все
-	r.Get("/products", func(w http.ResponseWriter, r *http.Request) {
-		products, _ := catService.GetAllProducts(r.Context())
-		json.NewEncoder(w).Encode(products)
-	})
-
-	// ЗАЩИЩЕННАЯ группа: только для тех, у кого есть токен
-	r.Group(func(r chi.Router) {
-		r.Use(httpmw.AuthMiddleware) // Вешаем нашего охранника на эту группу
-
-		r.Post("/products", func(w http.ResponseWriter, r *http.Request) {
-			var p pg.Product
-			json.NewDecoder(r.Body).Decode(&p)
-			id, err := catService.AddProduct(r.Context(), p)
-			if err != nil {
-				http.Error(w, err.Error(), 400)
-				return
-			}
-			json.NewEncoder(w).Encode(map[string]int64{"id": id})
-		})
-	})
+	// События об изменениях меню уходят в RabbitMQ из outbox после коммита
+	go events.NewRelay(pool, cfg.RabbitMQURL, time.Second).Run(context.Background())
+
+	r := chi.NewRouter()
+	catHandler.RegisterRoutes(r)
 
 	log.Println("Сервис каталога запущен на порту :8080")
 	http.ListenAndServe(":8080", r)
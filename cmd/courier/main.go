@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/JuniorCrafter/fooddelivery/internal/courier/repo"
 	"github.com/JuniorCrafter/fooddelivery/internal/courier/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/config"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/db"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/httpmw"
@@ -46,11 +48,13 @@ func main() {
 			json.NewEncoder(w).Encode(orders)
 		})
 
+		// Брать заказы и менять их статус может только курьер; кто он — берем из токена, а не из тела
+		courierOnly := r.With(httpmw.RequireRole("courier"))
+
 		// 2. ПРИНЯТЬ ЗАКАЗ (Тот самый пропавший метод)
-		r.Post("/accept", func(w http.ResponseWriter, r *http.Request) {
+		courierOnly.Post("/accept", func(w http.ResponseWriter, r *http.Request) {
 			var input struct {
-				CourierID int64 `json:"courier_id"`
-				OrderID   int64 `json:"order_id"`
+				OrderID int64 `json:"order_id"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
@@ -58,9 +62,10 @@ func main() {
 			}
 
 			// Вызываем сервис (получаем имя курьера и ошибку)
-			courierName, err := courierServ.TakeOrder(r.Context(), input.CourierID, input.OrderID)
+			userID, _ := httpmw.UserID(r.Context())
+			courierName, err := courierServ.TakeOrder(r.Context(), userID, input.OrderID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				writeStatusError(w, err, http.StatusBadRequest)
 				return
 			}
 
@@ -74,7 +79,7 @@ func main() {
 		})
 
 		// 3. Изменить статус заказа (в пути, доставлен и т.д.)
-		r.Post("/status", func(w http.ResponseWriter, r *http.Request) {
+		courierOnly.Post("/status", func(w http.ResponseWriter, r *http.Request) {
 			var input struct {
 				OrderID int64  `json:"order_id"`
 				Status  string `json:"status"`
@@ -84,9 +89,10 @@ func main() {
 				return
 			}
 
-			err := courierServ.ChangeStatus(r.Context(), input.OrderID, input.Status)
+			userID, _ := httpmw.UserID(r.Context())
+			err := courierServ.ChangeStatus(r.Context(), userID, input.OrderID, input.Status)
 			if err != nil {
-				http.Error(w, "Не удалось обновить статус", http.StatusInternalServerError)
+				writeStatusError(w, err, http.StatusInternalServerError)
 				return
 			}
 
@@ -133,3 +139,22 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// writeStatusError переводит ошибки машины состояний заказа в HTTP-коды;
+// остальные ошибки отдаются с кодом fallback, как раньше
+func writeStatusError(w http.ResponseWriter, err error, fallback int) {
+	switch {
+	case errors.Is(err, status.ErrUnknown):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, status.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, status.ErrIllegalTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, status.ErrNotAllowed), errors.Is(err, repo.ErrNotCourier):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case fallback == http.StatusInternalServerError:
+		http.Error(w, "Не удалось обновить статус", fallback)
+	default:
+		http.Error(w, err.Error(), fallback)
+	}
+}
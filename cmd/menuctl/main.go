@@ -0,0 +1,121 @@
+// menuctl — консольная утилита для массового импорта и экспорта меню напрямую в базу.
+//
+//	menuctl import -file menu.csv -dry-run
+//	menuctl export -format json > backup.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/menuio"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/config"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/db"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Использование:")
+	fmt.Fprintln(os.Stderr, "  menuctl import -file menu.csv [-format csv|json] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "  menuctl export [-format csv|json] [-out menu.json]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg := config.Load()
+	ctx := context.Background()
+	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к БД: %v", err)
+	}
+	defer pool.Close()
+
+	// Кэш тут не оборачиваем: после импорта через CLI записи в Redis истекут по TTL
+	catService := service.New(pg.New(pool))
+
+	switch os.Args[1] {
+	case "import":
+		runImport(ctx, catService, os.Args[2:])
+	case "export":
+		runExport(ctx, catService, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func runImport(ctx context.Context, s service.Service, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "путь к файлу меню")
+	format := fs.String("format", "", "csv или json (по умолчанию — по расширению файла)")
+	dryRun := fs.Bool("dry-run", false, "только проверить файл, ничего не записывая")
+	fs.Parse(args)
+
+	if *file == "" {
+		usage()
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	categories, items, err := menuio.Decode(*format, f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report, err := s.ImportMenu(ctx, categories, items, *dryRun)
+	if err != nil {
+		log.Fatalf("Импорт не удался: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	log.Printf("Всего: %d, создано: %d, обновлено: %d, с ошибками: %d (dry-run: %v)",
+		report.Total, report.Created, report.Updated, report.Failed, report.DryRun)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runExport(ctx context.Context, s service.Service, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", menuio.FormatJSON, "csv или json")
+	out := fs.String("out", "", "файл для выгрузки (по умолчанию stdout)")
+	fs.Parse(args)
+
+	menu, err := s.ExportMenu(ctx)
+	if err != nil {
+		log.Fatalf("Экспорт не удался: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := menuio.Encode(*format, w, menu); err != nil {
+		log.Fatal(err)
+	}
+}
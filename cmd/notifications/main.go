@@ -21,5 +21,7 @@ func main() {
 	log.Println("Notification Service успешно запущен...")
 
 	// 3. Начинаем слушать очередь
-	consumer.Listen()
+	if err := consumer.Listen(); err != nil {
+		log.Fatalf("Notification Service остановлен: %v", err)
+	}
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/cart"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/go-chi/chi/v5"
+)
+
+// cartRoutes — корзина текущего пользователя. Без Redis корзины нет: ручки отвечают 503.
+func cartRoutes(r chi.Router, carts service.CartService) {
+	r.Route("/cart", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if carts == nil {
+					http.Error(w, "Корзина временно недоступна", http.StatusServiceUnavailable)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+
+		// Корзина с ценами из каталога на этот момент
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			c, err := carts.Get(r.Context(), userID)
+			writeCart(w, c, err)
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			if err := carts.Clear(r.Context(), userID); err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		// {"product_id": 12, "quantity": 2, "options": [{"option_id": 3}]}; такая же позиция — прибавится количество
+		r.Post("/items", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			var item repo.OrderItem
+			if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			c, err := carts.AddItem(r.Context(), userID, item)
+			writeCart(w, c, err)
+		})
+
+		// {"quantity": 3}; 0 убирает позицию
+		r.Patch("/items/{line}", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			var input struct {
+				Quantity *int `json:"quantity"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Quantity == nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			c, err := carts.SetQuantity(r.Context(), userID, chi.URLParam(r, "line"), *input.Quantity)
+			writeCart(w, c, err)
+		})
+
+		r.Delete("/items/{line}", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			c, err := carts.RemoveItem(r.Context(), userID, chi.URLParam(r, "line"))
+			writeCart(w, c, err)
+		})
+
+		// Оформление корзины: тело как у POST /orders, но без items. Idempotency-Key работает так же
+		r.Post("/checkout", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			var input struct {
+				PromoCode string         `json:"promo_code"`
+				Delivery  *repo.Delivery `json:"delivery"`
+				DeliverAt *time.Time     `json:"deliver_at"`
+				Tip       money.Money    `json:"tip"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			id, replayed, err := carts.Checkout(r.Context(), service.CheckoutRequest{
+				UserID:         userID,
+				PromoCode:      input.PromoCode,
+				Delivery:       input.Delivery,
+				DeliverAt:      input.DeliverAt,
+				Tip:            input.Tip,
+				IdempotencyKey: r.Header.Get("Idempotency-Key"),
+			})
+			if err != nil {
+				if errors.Is(err, service.ErrEmptyCart) {
+					http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+					return
+				}
+				writePlaceError(w, err)
+				return
+			}
+			if replayed {
+				w.Header().Set("Idempotent-Replayed", "true")
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]int64{"order_id": id})
+		})
+	})
+}
+
+// writeCart отдает корзину или переводит ошибку корзины в HTTP-код
+func writeCart(w http.ResponseWriter, c service.Cart, err error) {
+	switch {
+	case errors.Is(err, cart.ErrLineNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, cart.ErrTooMany), errors.Is(err, cart.ErrTooManyLines):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case err != nil:
+		writePlaceError(w, err)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c)
+	}
+}
@@ -3,15 +3,31 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	catalogservice "github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
 	// Создадим позже или напишем тут
+	"github.com/JuniorCrafter/fooddelivery/internal/order/cart"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/delivery"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/receipt"
 	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
 	"github.com/JuniorCrafter/fooddelivery/internal/order/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment/fake"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/cache"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/config"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/db"
 	"github.com/JuniorCrafter/fooddelivery/internal/platform/httpmw"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -23,31 +39,463 @@ func main() {
 	}
 
 	repository := repo.New(pool)
-	orderService := service.New(repository)
+
+	// Заказы меняют остатки товаров, а каталог отдает их из кэша в Redis — сбрасываем его.
+	// Без Redis сбрасывать нечего: каталог тогда тоже работает без кэша.
+	var stockChanged service.StockChanged
+	rdb, err := cache.NewRedisClient(cfg.RedisAddr, "")
+	if err != nil {
+		log.Printf("Redis недоступен, кэш каталога после изменения остатков не сбрасывается, корзина не работает: %v", err)
+	} else {
+		catalogCache := cache.New(rdb, cfg.CatalogCacheTTL)
+		stockChanged = func(ctx context.Context) error {
+			return catalogCache.Invalidate(ctx, catalogservice.GenKey)
+		}
+	}
+	pricing, err := delivery.NewPricing(cfg.DeliveryFeeTiers, cfg.DeliveryFreeFrom, cfg.DeliveryMinBasket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.OrderSlotLength <= 0 || cfg.OrderSlotCapacity <= 0 {
+		log.Fatal("ORDER_SLOT_LENGTH и ORDER_SLOT_CAPACITY должны быть больше нуля")
+	}
+	slots := service.SlotPolicy{
+		Length:   cfg.OrderSlotLength,
+		Capacity: cfg.OrderSlotCapacity,
+		Lead:     cfg.OrderScheduleLead,
+		Horizon:  cfg.OrderScheduleHorizon,
+	}
+	orderService := service.New(repository, cfg.OrderReservationTTL, cfg.OrderIdempotencyTTL, pricing, slots, stockChanged)
+	var provider payment.Provider
+	switch cfg.PaymentProvider {
+	case "fake":
+		provider = fake.New(webhookSecret(cfg))
+	default:
+		log.Fatalf("Неизвестный PAYMENT_PROVIDER %q", cfg.PaymentProvider)
+	}
+	seller := receipt.Seller{Name: cfg.ReceiptSellerName, INN: cfg.ReceiptSellerINN, Address: cfg.ReceiptSellerAddress}
+	paymentService := service.NewPayments(repository, provider, seller, cfg.TipWindow)
+	reviewService := service.NewReviews(repository)
+	var cartService service.CartService
+	if rdb != nil {
+		cartService = service.NewCart(cart.NewStore(rdb, cfg.CartTTL), repository, orderService)
+	}
+
+	// order.cancelled и другие события заказов уходят в RabbitMQ из outbox после коммита
+	go events.NewRelay(pool, cfg.RabbitMQURL, time.Second).Run(context.Background())
+
+	// Раз в минуту отдаем кухне заказы ко времени, снимаем резервы с заказов, которые так и не оплатили,
+	// чистим старые ключи идемпотентности, списываем деньги за доставленные заказы и возвращаем за отмененные
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := orderService.ReleaseScheduledOrders(context.Background()); err != nil {
+				log.Printf("Ошибка выпуска заказов ко времени: %v", err)
+			}
+			if err := orderService.ExpireReservations(context.Background()); err != nil {
+				log.Printf("Ошибка снятия просроченных резервов: %v", err)
+			}
+			if err := orderService.ExpireIdempotencyKeys(context.Background()); err != nil {
+				log.Printf("Ошибка очистки ключей идемпотентности: %v", err)
+			}
+			if err := paymentService.SettlePayments(context.Background()); err != nil {
+				log.Printf("Ошибка списания и возврата платежей: %v", err)
+			}
+		}
+	}()
 
 	r := chi.NewRouter()
 
-	// Защищаем ручку создания заказа
+	// Все ручки заказов требуют токен
 	r.Group(func(r chi.Router) {
 		r.Use(httpmw.AuthMiddleware)
 
 		r.Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+			req, ok := decodeOrderRequest(w, r)
+			if !ok {
+				return
+			}
+			// Повтор с тем же Idempotency-Key не создает второй заказ, а возвращает первый
+			req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+			id, replayed, err := orderService.PlaceOrder(r.Context(), req)
+			if err != nil {
+				writePlaceError(w, err)
+				return
+			}
+			if replayed {
+				w.Header().Set("Idempotent-Replayed", "true")
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]int64{"order_id": id})
+		})
+
+		// Расчет заказа без оформления: то же тело, что у POST /orders
+		r.Post("/orders/quote", func(w http.ResponseWriter, r *http.Request) {
+			req, ok := decodeOrderRequest(w, r)
+			if !ok {
+				return
+			}
+			quote, err := orderService.Quote(r.Context(), req)
+			if err != nil {
+				writePlaceError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(quote)
+		})
+
+		// Мои заказы: ?status=new,accepted&limit=20&cursor=<next_cursor прошлой страницы>
+		r.Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			f := repo.ListFilter{UserID: userID}
+			q := r.URL.Query()
+			if raw := q.Get("status"); raw != "" {
+				for _, part := range strings.Split(raw, ",") {
+					st, err := status.Parse(strings.TrimSpace(part))
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					f.Statuses = append(f.Statuses, st)
+				}
+			}
+			var err error
+			if raw := q.Get("limit"); raw != "" {
+				if f.Limit, err = strconv.Atoi(raw); err != nil || f.Limit <= 0 {
+					http.Error(w, service.ErrInvalidLimit.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if raw := q.Get("cursor"); raw != "" {
+				if f.Before, err = strconv.ParseInt(raw, 10, 64); err != nil || f.Before <= 0 {
+					http.Error(w, "Некорректный cursor", http.StatusBadRequest)
+					return
+				}
+			}
+
+			page, err := orderService.ListOrders(r.Context(), f)
+			switch {
+			case errors.Is(err, service.ErrInvalidLimit):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case err != nil:
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(page)
+		})
+
+		// Заказ целиком: позиции, курьер и история статусов. Только владельцу
+		r.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			id, ok := orderID(w, r)
+			if !ok {
+				return
+			}
+			order, err := orderService.GetOrder(r.Context(), userID, id)
+			if err != nil {
+				writeStatusError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(order)
+		})
+
+		// Отмена клиентом своего заказа или администратором любого: {"reason": "..."}
+		r.Post("/orders/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := currentUser(w, r)
+			if !ok {
+				return
+			}
+			id, ok := orderID(w, r)
+			if !ok {
+				return
+			}
 			var input struct {
-				UserID int64            `json:"user_id"`
-				Items  []repo.OrderItem `json:"items"`
+				Reason string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			actor := status.Actor{Type: status.ActorClient, ID: userID}
+			if tokenActor(r).Type == status.ActorAdmin {
+				actor.Type = status.ActorAdmin
 			}
-			json.NewDecoder(r.Body).Decode(&input)
 
-			id, err := orderService.PlaceOrder(r.Context(), input.UserID, input.Items)
+			cancellation, err := orderService.CancelOrder(r.Context(), id, actor, input.Reason)
 			if err != nil {
+				writeStatusError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(cancellation)
+		})
+
+		// Ресторан двигает заказ по кухне: {"status": "cooking"} / {"status": "ready"}.
+		// Какие статусы можно ставить, решает машина состояний по роли из токена
+		r.With(httpmw.RequireRole("restaurant", "admin")).Post("/orders/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+			id, ok := orderID(w, r)
+			if !ok {
+				return
+			}
+			var input struct {
+				Status string `json:"status"`
+				Reason string `json:"reason"` // обязательна для cancelled
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			to, err := status.Parse(input.Status)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := orderService.ChangeStatus(r.Context(), id, to, tokenActor(r), input.Reason); err != nil {
+				writeStatusError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		// История статусов: кто и когда менял. Только владельцу, курьеру заказа и администратору
+		r.Get("/orders/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := currentUser(w, r); !ok {
+				return
+			}
+			id, ok := orderID(w, r)
+			if !ok {
+				return
+			}
+			history, err := orderService.StatusHistory(r.Context(), id, tokenActor(r))
+			if err != nil {
+				writeStatusError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(history)
+		})
+
+		cartRoutes(r, cartService)
+		paymentRoutes(r, paymentService)
+		reviewRoutes(r, reviewService)
+	})
+
+	// Рейтинги товаров и курьеров видны всем, в том числе до входа
+	ratingRoutes(r, reviewService)
+
+	// Вебхук провайдера приходит без токена: подлинность проверяется подписью
+	r.Post("/payments/webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+			return
+		}
+		err = paymentService.HandleWebhook(r.Context(), body, r.Header)
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case errors.Is(err, payment.ErrInvalidWebhook):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, payment.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Промокоды и модерация отзывов — только для администратора
+	r.Group(func(r chi.Router) {
+		r.Use(httpmw.AuthMiddleware)
+		r.Use(httpmw.RequireRole("admin"))
+
+		r.Post("/promo-codes", func(w http.ResponseWriter, r *http.Request) {
+			var input promo.Code
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+				return
+			}
+			code, err := orderService.CreatePromoCode(r.Context(), input)
+			switch {
+			case errors.Is(err, promo.ErrInvalid):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case errors.Is(err, promo.ErrDuplicate):
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case err != nil:
 				http.Error(w, err.Error(), 500)
 				return
 			}
+			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
-			json.NewEncoder(w).Encode(map[string]int64{"order_id": id})
+			json.NewEncoder(w).Encode(code)
 		})
+
+		r.Get("/promo-codes", func(w http.ResponseWriter, r *http.Request) {
+			codes, err := orderService.ListPromoCodes(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(codes)
+		})
+
+		r.Delete("/promo-codes/{code}", func(w http.ResponseWriter, r *http.Request) {
+			err := orderService.DeactivatePromoCode(r.Context(), chi.URLParam(r, "code"))
+			switch {
+			case errors.Is(err, promo.ErrNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			case err != nil:
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		reviewAdminRoutes(r, reviewService)
 	})
 
 	log.Println("Сервис заказов запущен на порту :8082")
 	http.ListenAndServe(":8082", r)
 }
+
+// decodeOrderRequest читает тело POST /orders. Заказ оформляется на того, чей токен, а не на user_id из тела
+func decodeOrderRequest(w http.ResponseWriter, r *http.Request) (service.OrderRequest, bool) {
+	var input struct {
+		UserID    int64            `json:"user_id"`
+		Items     []repo.OrderItem `json:"items"`
+		PromoCode string           `json:"promo_code"`
+		Delivery  *repo.Delivery   `json:"delivery"`
+		DeliverAt *time.Time       `json:"deliver_at"` // начало слота, RFC 3339; нет — как можно скорее
+		Tip       money.Money      `json:"tip"`        // чаевые курьеру; нет — без чаевых
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return service.OrderRequest{}, false
+	}
+	if userID, ok := httpmw.UserID(r.Context()); ok {
+		input.UserID = userID
+	}
+	return service.OrderRequest{
+		UserID:    input.UserID,
+		Items:     input.Items,
+		PromoCode: input.PromoCode,
+		Delivery:  input.Delivery,
+		DeliverAt: input.DeliverAt,
+		Tip:       input.Tip,
+	}, true
+}
+
+// writePlaceError переводит ошибки расчета и создания заказа в HTTP-коды
+func writePlaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repo.ErrProductNotFound), errors.Is(err, service.ErrInvalidOptions),
+		errors.Is(err, service.ErrEmptyOrder), errors.Is(err, service.ErrInvalidQty),
+		errors.Is(err, service.ErrInvalidIdempotencyKey), errors.Is(err, service.ErrNoDeliveryAddress),
+		errors.Is(err, service.ErrInvalidSlot), errors.Is(err, service.ErrInvalidTip):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repo.ErrIdempotencyMismatch), isPromoError(err),
+		errors.Is(err, delivery.ErrTooFar), errors.Is(err, delivery.ErrMinBasket):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, repo.ErrProductUnavailable), errors.Is(err, repo.ErrOutOfStock), errors.Is(err, repo.ErrPriceChanged),
+		errors.Is(err, service.ErrKitchenClosed), errors.Is(err, repo.ErrSlotFull):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// devWebhookSecret подписывает вебхуки только при локальном запуске (APP_ENV=dev)
+const devWebhookSecret = "dev-payment-secret"
+
+// webhookSecret не дает запустить сервис с общеизвестным секретом: без PAYMENT_WEBHOOK_SECRET
+// любой мог бы подделать вебхук об оплате
+func webhookSecret(cfg *config.Config) string {
+	if cfg.PaymentWebhookSecret != "" {
+		return cfg.PaymentWebhookSecret
+	}
+	if !cfg.IsDev() {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET не задан; секрет по умолчанию допускается только при APP_ENV=dev")
+	}
+	log.Printf("PAYMENT_WEBHOOK_SECRET не задан, вебхуки подписываются dev-секретом")
+	return devWebhookSecret
+}
+
+// tokenActor — кто действует по роли из токена; токен без роли считается клиентским
+func tokenActor(r *http.Request) status.Actor {
+	actor := status.Actor{Type: status.ActorClient}
+	actor.ID, _ = httpmw.UserID(r.Context())
+	switch httpmw.Role(r.Context()) {
+	case "admin":
+		actor.Type = status.ActorAdmin
+	case "restaurant":
+		actor.Type = status.ActorRestaurant
+	case "courier":
+		actor.Type = status.ActorCourier
+	}
+	return actor
+}
+
+// isPromoError — промокод есть в запросе, но к этому заказу не подходит
+func isPromoError(err error) bool {
+	for _, target := range []error{promo.ErrNotFound, promo.ErrInactive, promo.ErrMinBasket, promo.ErrNotApplicable,
+		promo.ErrExhausted, promo.ErrUserLimit, promo.ErrFirstOrder} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentUser — id пользователя из токена; старые токены без user_id не подходят
+func currentUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, ok := httpmw.UserID(r.Context())
+	if !ok {
+		http.Error(w, "В токене нет пользователя, войдите заново", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
+
+func orderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Некорректный ID заказа", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeStatusError переводит ошибки машины состояний в HTTP-коды
+func writeStatusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoReason), errors.Is(err, service.ErrReasonTooLong):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, status.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, status.ErrIllegalTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, status.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
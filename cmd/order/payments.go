@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/go-chi/chi/v5"
+)
+
+// paymentRoutes — оплата своего заказа. Пока заказ не оплачен, он в статусе awaiting_payment,
+// и ни кухня, ни курьеры его не видят.
+func paymentRoutes(r chi.Router, payments service.PaymentService) {
+	// {"token": "tok_..."} — токен карты от платежной формы провайдера
+	r.Post("/orders/{id}/payment", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		var input struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+			return
+		}
+		p, err := payments.Pay(r.Context(), userID, id, input.Token)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// pending — банк ответит позже, статус можно узнать через GET
+		if p.Status == payment.Pending {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		json.NewEncoder(w).Encode(p)
+	})
+
+	r.Get("/orders/{id}/payment", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		p, err := payments.GetPayment(r.Context(), userID, id)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	})
+
+	// Чаевые курьеру после доставки: {"amount": "150.00", "token": "tok_..."}
+	r.Post("/orders/{id}/tip", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		var input struct {
+			Amount money.Money `json:"amount"`
+			Token  string      `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+			return
+		}
+		t, err := payments.Tip(r.Context(), userID, id, input.Amount, input.Token)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	})
+
+	r.Get("/orders/{id}/tip", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		t, err := payments.GetTip(r.Context(), userID, id)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	})
+
+	// Чек: ?format=json (по умолчанию), ?format=html или ?format=pdf — файлом для скачивания и печати
+	r.Get("/orders/{id}/receipt", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "html" && format != "pdf" {
+			http.Error(w, "Формат чека: json, html или pdf", http.StatusBadRequest)
+			return
+		}
+		rc, err := payments.Receipt(r.Context(), userID, id)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		switch format {
+		case "html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%s.html"`, rc.Number))
+			if err := rc.WriteHTML(w); err != nil {
+				log.Printf("Чек заказа %d: %v", id, err)
+			}
+			return
+		case "pdf":
+			// PDF собираем целиком в памяти: при ошибке еще можно ответить 500, а не оборванным файлом
+			var buf bytes.Buffer
+			if err := rc.WritePDF(&buf); err != nil {
+				log.Printf("Чек заказа %d: %v", id, err)
+				http.Error(w, "Не удалось сформировать чек", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%s.pdf"`, rc.Number))
+			w.Write(buf.Bytes())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rc)
+	})
+}
+
+// writePaymentError переводит ошибки оплаты в HTTP-коды
+func writePaymentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoPaymentToken), errors.Is(err, service.ErrInvalidTip):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, status.ErrOrderNotFound), errors.Is(err, payment.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, payment.ErrDeclined):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, repo.ErrNotPayable), errors.Is(err, service.ErrNoReceipt),
+		errors.Is(err, repo.ErrNotTippable), errors.Is(err, repo.ErrTipExists), errors.Is(err, repo.ErrTipWindowClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrPaymentProvider):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/review"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/go-chi/chi/v5"
+)
+
+// reviewRoutes — отзыв клиента на свой доставленный заказ
+func reviewRoutes(r chi.Router, reviews service.ReviewService) {
+	// {"food": {"score": 5, "comment": "..."}, "courier": {"score": 4}}; любую из оценок можно не ставить
+	r.Post("/orders/{id}/review", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		var input review.Review
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+			return
+		}
+		rv, err := reviews.Create(r.Context(), userID, id, review.Review{Food: input.Food, Courier: input.Courier})
+		if err != nil {
+			writeReviewError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rv)
+	})
+
+	r.Get("/orders/{id}/review", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		rv, err := reviews.Get(r.Context(), userID, id)
+		writeReview(w, rv, err)
+	})
+}
+
+// ratingRoutes — рейтинги и опубликованные отзывы, доступны без токена
+func ratingRoutes(r chi.Router, reviews service.ReviewService) {
+	r.Get("/products/{id}/rating", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "Некорректный ID товара")
+		if !ok {
+			return
+		}
+		s, err := reviews.ProductRating(r.Context(), id)
+		writeReview(w, s, err)
+	})
+
+	// ?limit=20&cursor=<next_cursor прошлой страницы>
+	r.Get("/products/{id}/reviews", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "Некорректный ID товара")
+		if !ok {
+			return
+		}
+		limit, before, ok := pageParams(w, r)
+		if !ok {
+			return
+		}
+		page, err := reviews.ProductReviews(r.Context(), id, before, limit)
+		writeReview(w, page, err)
+	})
+
+	r.Get("/couriers/{id}/rating", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "Некорректный ID курьера")
+		if !ok {
+			return
+		}
+		s, err := reviews.CourierRating(r.Context(), id)
+		writeReview(w, s, err)
+	})
+}
+
+// reviewAdminRoutes — модерация отзывов; группа уже закрыта httpmw.RequireRole("admin")
+func reviewAdminRoutes(r chi.Router, reviews service.ReviewService) {
+	// ?hidden=true|false&limit=20&cursor=...
+	r.Get("/reviews", func(w http.ResponseWriter, r *http.Request) {
+		var f review.Filter
+		if raw := r.URL.Query().Get("hidden"); raw != "" {
+			hidden, err := strconv.ParseBool(raw)
+			if err != nil {
+				http.Error(w, "hidden: true или false", http.StatusBadRequest)
+				return
+			}
+			f.Hidden = &hidden
+		}
+		var ok bool
+		if f.Limit, f.Before, ok = pageParams(w, r); !ok {
+			return
+		}
+		page, err := reviews.List(r.Context(), f)
+		writeReview(w, page, err)
+	})
+
+	// {"reason": "оскорбления"}
+	r.Post("/reviews/{id}/hide", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "Некорректный ID отзыва")
+		if !ok {
+			return
+		}
+		var input struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+			return
+		}
+		rv, err := reviews.Hide(r.Context(), id, input.Reason)
+		writeReview(w, rv, err)
+	})
+
+	r.Post("/reviews/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "Некорректный ID отзыва")
+		if !ok {
+			return
+		}
+		rv, err := reviews.Restore(r.Context(), id)
+		writeReview(w, rv, err)
+	})
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, msg string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, msg, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// pageParams читает limit и cursor; 0 — не заданы
+func pageParams(w http.ResponseWriter, r *http.Request) (limit int, before int64, ok bool) {
+	q := r.URL.Query()
+	var err error
+	if raw := q.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			http.Error(w, service.ErrInvalidLimit.Error(), http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	if raw := q.Get("cursor"); raw != "" {
+		if before, err = strconv.ParseInt(raw, 10, 64); err != nil || before <= 0 {
+			http.Error(w, "Некорректный cursor", http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return limit, before, true
+}
+
+// writeReview отдает v или переводит ошибку отзывов в HTTP-код
+func writeReview(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeReviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, review.ErrInvalid), errors.Is(err, service.ErrInvalidLimit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, review.ErrNotFound), errors.Is(err, status.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, review.ErrDuplicate), errors.Is(err, review.ErrNotReviewable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, review.ErrNoCourier):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
@@ -30,7 +30,7 @@ CREATE TABLE IF NOT EXISTS orders (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL REFERENCES users(id),
     courier_id INTEGER REFERENCES couriers(id), -- Заполняется после 'accept'
-    status TEXT NOT NULL DEFAULT 'new', -- new, accepted, cooking, delivering, completed
+    status TEXT NOT NULL DEFAULT 'new', -- см. internal/order/status и 010_order_status_history.sql
     total_price DECIMAL(10, 2) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -0,0 +1,32 @@
+-- Группы опций товара: размер, добавки, "выберите 2 соуса" (Catalog Service)
+CREATE TABLE IF NOT EXISTS product_option_groups (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    min_select INTEGER NOT NULL DEFAULT 0, -- 0 — группа необязательная
+    max_select INTEGER NOT NULL DEFAULT 1,
+    position INTEGER NOT NULL DEFAULT 0,   -- порядок показа в меню
+    CHECK (min_select >= 0 AND max_select >= 1 AND min_select <= max_select)
+);
+
+CREATE TABLE IF NOT EXISTS product_options (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES product_option_groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    price_delta DECIMAL(10, 2) NOT NULL DEFAULT 0, -- надбавка к цене товара
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+-- Выбранные опции позиции заказа (Order Service).
+-- option_id без внешнего ключа: меню могут пересобрать, а заказ должен хранить снимок названия и цены
+CREATE TABLE IF NOT EXISTS order_item_options (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    option_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    price_delta DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_option_groups_product_id ON product_option_groups(product_id);
+CREATE INDEX IF NOT EXISTS idx_options_group_id ON product_options(group_id);
+CREATE INDEX IF NOT EXISTS idx_order_item_options_item_id ON order_item_options(order_item_id);
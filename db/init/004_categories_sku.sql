@@ -0,0 +1,12 @@
+-- Категории меню и внешний артикул товара для массового импорта (Catalog Service)
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id);
+-- sku — артикул из учетной системы ресторана, по нему импорт понимает "создать или обновить"
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT UNIQUE;
+
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
@@ -0,0 +1,37 @@
+-- Кухни (рестораны), которым принадлежат товары, и их расписание (Catalog Service)
+CREATE TABLE IF NOT EXISTS kitchens (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'Europe/Moscow', -- часы работы задаются по местному времени кухни
+    lat DOUBLE PRECISION,
+    lon DOUBLE PRECISION
+);
+
+-- Недельный график: несколько интервалов в день (обеденный перерыв), closes_at < opens_at — работа после полуночи
+CREATE TABLE IF NOT EXISTS kitchen_hours (
+    id SERIAL PRIMARY KEY,
+    kitchen_id INTEGER NOT NULL REFERENCES kitchens(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 — воскресенье
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL
+);
+
+-- Праздники и особые дни: полностью закрыто или другой график
+CREATE TABLE IF NOT EXISTS kitchen_exceptions (
+    id SERIAL PRIMARY KEY,
+    kitchen_id INTEGER NOT NULL REFERENCES kitchens(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    is_closed BOOLEAN NOT NULL DEFAULT TRUE,
+    opens_at TIME,
+    closes_at TIME,
+    note TEXT,
+    UNIQUE (kitchen_id, date)
+);
+
+-- Товар принадлежит кухне; available_from/until — окно позиции меню (завтраки до 11:00)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS kitchen_id INTEGER REFERENCES kitchens(id);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS available_from TIME;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS available_until TIME;
+
+CREATE INDEX IF NOT EXISTS idx_products_kitchen_id ON products(kitchen_id);
+CREATE INDEX IF NOT EXISTS idx_kitchen_hours_kitchen_id ON kitchen_hours(kitchen_id);
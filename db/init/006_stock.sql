@@ -0,0 +1,18 @@
+-- Остатки товаров и их резервирование заказами (Catalog + Order Service)
+-- stock — сколько порций еще можно продать; NULL — без ограничения
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INTEGER CHECK (stock >= 0);
+
+-- Резерв списывается из stock в транзакции создания заказа и возвращается при отмене заказа
+-- или если заказ слишком долго висит в статусе new (см. ORDER_RESERVATION_TTL)
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP WITH TIME ZONE -- NULL — порции всё еще за заказом
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_order_id ON stock_reservations(order_id);
+-- Поиск зависших заказов для снятия резерва по таймауту
+CREATE INDEX IF NOT EXISTS idx_orders_new_created_at ON orders(created_at) WHERE status = 'new';
@@ -0,0 +1,9 @@
+-- Переводы названия и описания товаров (Catalog Service).
+-- Основной язык (ru) хранится в самой таблице products, здесь — только остальные.
+CREATE TABLE IF NOT EXISTS product_translations (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    locale TEXT NOT NULL, -- "en", "en-gb" в нижнем регистре
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (product_id, locale)
+);
@@ -0,0 +1,8 @@
+-- Аллергены и пищевая ценность товаров (Catalog Service)
+-- allergens: NULL — ресторан не указал состав, '{}' — аллергенов нет
+ALTER TABLE products ADD COLUMN IF NOT EXISTS allergens TEXT[];
+-- КБЖУ на порцию; NULL — не указано
+ALTER TABLE products ADD COLUMN IF NOT EXISTS kcal NUMERIC(7, 1) CHECK (kcal >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS protein NUMERIC(7, 1) CHECK (protein >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS fat NUMERIC(7, 1) CHECK (fat >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS carbs NUMERIC(7, 1) CHECK (carbs >= 0);
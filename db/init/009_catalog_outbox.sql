@@ -0,0 +1,12 @@
+-- Outbox событий каталога: пишется в транзакции изменения меню,
+-- публикуется в RabbitMQ фоновым relay после коммита (Catalog Service)
+CREATE TABLE IF NOT EXISTS catalog_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE -- NULL — еще не отправлено
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_outbox_pending ON catalog_outbox(id) WHERE published_at IS NULL;
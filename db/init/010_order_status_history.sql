@@ -0,0 +1,19 @@
+-- Машина состояний заказа (пакет internal/order/status) и история переходов (Order + Courier Service)
+DO $$
+BEGIN
+    ALTER TABLE orders ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('new', 'accepted', 'cooking', 'ready', 'delivering', 'completed', 'cancelled', 'failed'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT, -- NULL у записи о создании заказа
+    to_status TEXT NOT NULL,
+    actor_type TEXT NOT NULL, -- client, courier, restaurant, system
+    actor_id INTEGER,         -- id пользователя или курьера, если известен
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
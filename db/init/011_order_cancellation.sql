@@ -0,0 +1,14 @@
+-- Отмена заказов (Order Service): причина перехода в истории статусов
+-- и outbox событий заказов, который публикуется в RabbitMQ (exchange order.events)
+ALTER TABLE order_status_history ADD COLUMN IF NOT EXISTS reason TEXT;
+
+CREATE TABLE IF NOT EXISTS order_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE -- NULL — еще не отправлено
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_outbox_pending ON order_outbox(id) WHERE published_at IS NULL;
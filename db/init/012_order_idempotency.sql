@@ -0,0 +1,13 @@
+-- Ключи идемпотентности создания заказа (Order Service): повтор POST /orders с тем же
+-- Idempotency-Key возвращает уже созданный заказ. Ответ на создание — только номер заказа,
+-- поэтому вместе с ключом храним хэш тела запроса и order_id
+CREATE TABLE IF NOT EXISTS order_idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL, -- sha256 значимых полей запроса
+    order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE, -- NULL, пока транзакция создания не завершилась
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_idempotency_keys_created_at ON order_idempotency_keys(created_at);
@@ -0,0 +1,14 @@
+-- Порядок и повторы в outbox (internal/platform/outbox), общие для catalog_outbox и order_outbox:
+-- ordering_key — события с одним ключом (у заказов это id заказа) публикуются строго по очереди;
+-- attempts / next_attempt_at / last_error — повтор с нарастающей паузой, если брокер не принял сообщение
+ALTER TABLE catalog_outbox ADD COLUMN IF NOT EXISTS ordering_key TEXT;
+ALTER TABLE catalog_outbox ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE catalog_outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE catalog_outbox ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+ALTER TABLE order_outbox ADD COLUMN IF NOT EXISTS ordering_key TEXT;
+ALTER TABLE order_outbox ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE order_outbox ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_order_outbox_ordering_key ON order_outbox(ordering_key, id) WHERE published_at IS NULL;
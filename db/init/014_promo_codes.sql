@@ -0,0 +1,34 @@
+-- Промокоды и скидки (Order Service), правила описаны в internal/order/promo
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL, -- в верхнем регистре
+    kind TEXT NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    percent INTEGER NOT NULL DEFAULT 0,           -- для percent: 1..100
+    amount DECIMAL(10, 2) NOT NULL DEFAULT 0,     -- для fixed
+    min_basket DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    first_order_only BOOLEAN NOT NULL DEFAULT FALSE,
+    max_uses INTEGER,       -- NULL — без общего лимита
+    per_user_limit INTEGER, -- NULL — без лимита на пользователя
+    used_count INTEGER NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP WITH TIME ZONE,
+    valid_until TIMESTAMP WITH TIME ZONE,
+    product_ids INTEGER[] NOT NULL DEFAULT '{}',  -- пусто вместе с category_ids — скидка на весь заказ
+    category_ids INTEGER[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Кто и в каком заказе использовал промокод; отмена заказа возвращает использование
+CREATE TABLE IF NOT EXISTS promo_code_usages (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    order_id INTEGER UNIQUE NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_code_usages_code_user ON promo_code_usages(promo_code_id, user_id);
+
+-- Скидка хранится на заказе: total_price — уже со скидкой
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- Заказы ко времени (Order Service): заказ спит в статусе scheduled до release_at,
+-- потом планировщик переводит его в new — с этого момента его видят кухня и курьеры
+DO $$
+BEGIN
+    ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+    ALTER TABLE orders ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('scheduled', 'new', 'accepted', 'cooking', 'ready', 'delivering', 'completed', 'cancelled', 'failed'));
+END $$;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS deliver_at TIMESTAMP WITH TIME ZONE;  -- начало слота доставки, NULL — "как можно скорее"
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS release_at TIMESTAMP WITH TIME ZONE;  -- когда отдать заказ на кухню
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS released_at TIMESTAMP WITH TIME ZONE; -- когда отдали; от него считается таймаут резерва
+
+CREATE INDEX IF NOT EXISTS idx_orders_release_at ON orders(release_at) WHERE status = 'scheduled';
+
+-- Сколько заказов уже записано на слот; лимит — ORDER_SLOT_CAPACITY
+CREATE TABLE IF NOT EXISTS delivery_slots (
+    slot_start TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    booked INTEGER NOT NULL DEFAULT 0
+);
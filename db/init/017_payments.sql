@@ -0,0 +1,29 @@
+-- Оплата заказов (Order Service): новый заказ ждет оплаты в статусе awaiting_payment,
+-- кухне и курьерам он уходит только после того, как провайдер заблокировал сумму на карте
+DO $$
+BEGIN
+    ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+    ALTER TABLE orders ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('awaiting_payment', 'scheduled', 'new', 'accepted', 'cooking', 'ready', 'delivering', 'completed', 'cancelled', 'failed'));
+END $$;
+
+-- Один платеж на заказ; после отказа банка та же строка начинается заново (attempt + 1)
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id),
+    provider VARCHAR(50) NOT NULL,
+    provider_payment_id VARCHAR(255),
+    amount DECIMAL(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'authorized', 'declined', 'failed', 'captured', 'refunded')),
+    attempt INTEGER NOT NULL DEFAULT 1,
+    -- Чем закончить платеж отмененного заказа: capture — еда уже потрачена по вине клиента, иначе refund
+    settle_action VARCHAR(10) CHECK (settle_action IN ('capture', 'refund')),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_id ON payments(provider, provider_payment_id);
+-- Платежи, которые еще нужно списать или вернуть
+CREATE INDEX IF NOT EXISTS idx_payments_authorized ON payments(order_id) WHERE status = 'authorized';
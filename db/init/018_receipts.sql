@@ -0,0 +1,13 @@
+-- Чеки заказов: налоговая категория товара (ставка НДС) и снимок на позиции заказа —
+-- чек печатается по ставкам и скидкам на момент покупки, даже если каталог потом изменится
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_category VARCHAR(10) NOT NULL DEFAULT 'vat20'
+    CHECK (tax_category IN ('vat20', 'vat10', 'vat0', 'none'));
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_category VARCHAR(10) NOT NULL DEFAULT 'vat20'
+    CHECK (tax_category IN ('vat20', 'vat10', 'vat0', 'none'));
+-- Доля скидки по промокоду, которая пришлась на позицию (на все штуки сразу)
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+-- Сколько и когда реально списано или возвращено: по этим данным печатается чек
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settled_amount DECIMAL(10, 2);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP WITH TIME ZONE;
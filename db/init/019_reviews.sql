@@ -0,0 +1,23 @@
+-- Отзывы на доставленные заказы (Order Service), правила описаны в internal/order/review
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER UNIQUE NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    courier_id INTEGER REFERENCES couriers(id), -- кто вез заказ; NULL, если курьера не оценивали
+    food_score SMALLINT CHECK (food_score BETWEEN 1 AND 5),
+    food_comment TEXT,
+    courier_score SMALLINT CHECK (courier_score BETWEEN 1 AND 5),
+    courier_comment TEXT,
+    -- Скрытые модератором отзывы не участвуют в рейтингах
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_reason TEXT,
+    moderated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (food_score IS NOT NULL OR courier_score IS NOT NULL),
+    CHECK (courier_score IS NULL OR courier_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_courier ON reviews(courier_id) WHERE NOT hidden AND courier_score IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reviews_hidden ON reviews(hidden, id);
+-- Рейтинг товара собирается через позиции заказов
+CREATE INDEX IF NOT EXISTS idx_order_items_product ON order_items(product_id);
@@ -0,0 +1,22 @@
+-- Чаевые курьеру (Order Service): отдельно от total_price, целиком достаются курьеру,
+-- который доставил заказ. Чаевые при оформлении блокируются вместе с оплатой заказа
+-- и списываются только после доставки; после доставки — отдельным платежом в течение TIP_WINDOW
+CREATE TABLE IF NOT EXISTS tips (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('checkout', 'after_delivery')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'failed', 'cancelled')),
+    -- Только у чаевых после доставки: у чаевых при оформлении платеж общий с заказом (payments)
+    provider VARCHAR(50),
+    provider_payment_id VARCHAR(255),
+    attempt INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- Начало текущей попытки списания: зависшую в pending попытку можно повторить
+    attempted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    paid_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Заработок курьера: выплаченные чаевые по его заказам
+CREATE INDEX IF NOT EXISTS idx_tips_paid ON tips(order_id) WHERE status = 'paid';
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/JuniorCrafter/fooddelivery/internal/auth/service"
@@ -40,6 +41,10 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Вызываем наш сервис
 	id, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Role)
+	if errors.Is(err, service.ErrRoleNotAllowed) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -16,6 +16,9 @@ type Service interface {
 	Login(ctx context.Context, email, password string) (string, error)
 }
 
+// ErrRoleNotAllowed — роль admin через регистрацию не выдается, только вручную в базе
+var ErrRoleNotAllowed = errors.New("при регистрации можно выбрать только роль client или courier")
+
 type authService struct {
 	repo repo.Repository
 }
@@ -26,6 +29,14 @@ func New(r repo.Repository) Service {
 }
 
 func (s *authService) Register(ctx context.Context, email, password, role string) (int64, error) {
+	switch role {
+	case "":
+		role = "client"
+	case "client", "courier":
+	default:
+		return 0, ErrRoleNotAllowed
+	}
+
 	// 1. Шифруем пароль. Cost 10 — это оптимальная сложность шифрования
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
 	if err != nil {
@@ -61,7 +72,7 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 		return "", errors.New("неверный пароль")
 	}
 
-	// 3. Если всё ок — создаем токен. Пока по умолчанию роль "client"
-	token, err := jwt.GenerateToken(u.ID, "client")
+	// 3. Если всё ок — создаем токен с ролью пользователя: по ней сервисы пускают администраторов
+	token, err := jwt.GenerateToken(u.ID, u.Role)
 	return token, err
 }
@@ -0,0 +1,120 @@
+// Package diet описывает аллергены и пищевую ценность товаров.
+// Список аллергенов закрытый, чтобы фильтр exclude_allergens=nuts не промахивался
+// из-за "орехи", "nut" и "Nuts" в разных карточках.
+package diet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownAllergen  = errors.New("неизвестный аллерген")
+	ErrInvalidNutrition = errors.New("некорректная пищевая ценность")
+)
+
+// Аллергены из списка обязательной маркировки (14 групп, как в ЕС и ТР ТС 022/2011)
+const (
+	Gluten      = "gluten"
+	Crustaceans = "crustaceans"
+	Eggs        = "eggs"
+	Fish        = "fish"
+	Peanuts     = "peanuts"
+	Soy         = "soy"
+	Milk        = "milk"
+	Nuts        = "nuts" // орехи, кроме арахиса
+	Celery      = "celery"
+	Mustard     = "mustard"
+	Sesame      = "sesame"
+	Sulphites   = "sulphites"
+	Lupin       = "lupin"
+	Molluscs    = "molluscs"
+)
+
+var known = map[string]bool{
+	Gluten: true, Crustaceans: true, Eggs: true, Fish: true, Peanuts: true, Soy: true, Milk: true,
+	Nuts: true, Celery: true, Mustard: true, Sesame: true, Sulphites: true, Lupin: true, Molluscs: true,
+}
+
+// Разумные потолки для одной порции, всё выше — скорее опечатка. Заодно они намного ниже
+// предела колонок NUMERIC(7, 1), так что лишний ноль — это 400, а не ошибка базы
+const (
+	maxKcal  = 10000
+	maxGrams = 1000
+)
+
+// Nutrition — пищевая ценность одной порции
+type Nutrition struct {
+	Kcal    float64 `json:"kcal"`
+	Protein float64 `json:"protein"` // граммы
+	Fat     float64 `json:"fat"`
+	Carbs   float64 `json:"carbs"`
+}
+
+func (n Nutrition) Validate() error {
+	// NaN не меньше нуля и не больше потолка, поэтому проверяем его отдельно:
+	// Postgres такое сохранит, а отдать товар в JSON потом не получится
+	for _, v := range []float64{n.Kcal, n.Protein, n.Fat, n.Carbs} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%w: значения должны быть числами", ErrInvalidNutrition)
+		}
+	}
+	if n.Kcal < 0 || n.Protein < 0 || n.Fat < 0 || n.Carbs < 0 {
+		return fmt.Errorf("%w: значения не могут быть отрицательными", ErrInvalidNutrition)
+	}
+	if n.Kcal > maxKcal {
+		return fmt.Errorf("%w: %.0f ккал на порцию", ErrInvalidNutrition, n.Kcal)
+	}
+	if n.Protein > maxGrams || n.Fat > maxGrams || n.Carbs > maxGrams {
+		return fmt.Errorf("%w: больше %d г белков, жиров или углеводов на порцию", ErrInvalidNutrition, maxGrams)
+	}
+	return nil
+}
+
+// NormalizeAllergens проверяет список по справочнику, приводит к нижнему регистру,
+// убирает повторы и сортирует. Пустой (не nil) список означает "аллергенов нет".
+func NormalizeAllergens(allergens []string) ([]string, error) {
+	if allergens == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(allergens))
+	result := make([]string, 0, len(allergens))
+	for _, a := range allergens {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if !known[a] {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownAllergen, a)
+		}
+		if !seen[a] {
+			seen[a] = true
+			result = append(result, a)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ParseList разбирает "nuts, gluten" из параметра запроса или ячейки CSV
+func ParseList(s string) []string {
+	var list []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// Contains — есть ли в составе хоть один из аллергенов
+func Contains(allergens, any []string) bool {
+	for _, a := range any {
+		for _, b := range allergens {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package diet
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNutritionValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		n    Nutrition
+		ok   bool
+	}{
+		{"обычная порция", Nutrition{Kcal: 450, Protein: 20, Fat: 15.5, Carbs: 60}, true},
+		{"нули", Nutrition{}, true},
+		{"на потолке", Nutrition{Kcal: maxKcal, Protein: maxGrams, Fat: maxGrams, Carbs: maxGrams}, true},
+		{"отрицательные", Nutrition{Kcal: -1}, false},
+		{"слишком калорийно", Nutrition{Kcal: maxKcal + 1}, false},
+		{"слишком много белка", Nutrition{Protein: maxGrams + 0.1}, false},
+		{"переполнение NUMERIC", Nutrition{Carbs: 1e7}, false},
+		{"NaN калорий", Nutrition{Kcal: math.NaN()}, false},
+		{"NaN жиров", Nutrition{Fat: math.NaN()}, false},
+		{"+Inf", Nutrition{Protein: math.Inf(1)}, false},
+		{"-Inf", Nutrition{Carbs: math.Inf(-1)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.n.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("Validate() = %v, ожидали nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidNutrition) {
+				t.Fatalf("Validate() = %v, ожидали ErrInvalidNutrition", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeAllergens(t *testing.T) {
+	got, err := NormalizeAllergens([]string{" Nuts", "gluten", "nuts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != Gluten || got[1] != Nuts {
+		t.Fatalf("NormalizeAllergens() = %v", got)
+	}
+	if got, _ := NormalizeAllergens([]string{}); got == nil || len(got) != 0 {
+		t.Fatalf("пустой список должен остаться пустым, а не nil: %v", got)
+	}
+	if _, err := NormalizeAllergens([]string{"орехи"}); !errors.Is(err, ErrUnknownAllergen) {
+		t.Fatalf("ожидали ErrUnknownAllergen, получили %v", err)
+	}
+}
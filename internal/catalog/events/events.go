@@ -0,0 +1,75 @@
+// Package events — события об изменениях меню для других сервисов (поиск, кэши, заказы).
+//
+// Надежность обеспечивает transactional outbox: событие пишется в таблицу catalog_outbox
+// той же транзакцией, что и само изменение, а relay из internal/platform/outbox публикует
+// его в RabbitMQ только после коммита.
+// Доставка "как минимум один раз": потребители отбрасывают повторы по полю id.
+//
+// Схема сообщения (exchange catalog.events, тип topic, routing key = type):
+//
+//	{"id": 42, "type": "product.updated", "version": 1, "occurred_at": "...", "data": {...}}
+//
+// data по типам (версия 1):
+//   - product.created, product.updated — {"product": <товар в формате GET /products/{id}, со всеми переводами>}
+//   - product.deleted — {"product_id": 7}
+//   - availability.changed — {"product_ids": [7, 8], "is_available": false, "reason": "stop_list" | "stock"}
+//
+// Несовместимые изменения data поднимают version; новые поля добавляются без смены версии.
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/outbox"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Exchange — topic-exchange событий каталога; подписка, например, на "product.*"
+	Exchange = "catalog.events"
+	// SchemaVersion — текущая версия схемы data
+	SchemaVersion = 1
+	// Table — outbox каталога (db/init/009_catalog_outbox.sql)
+	Table = "catalog_outbox"
+)
+
+const (
+	ProductCreated      = "product.created"
+	ProductUpdated      = "product.updated"
+	ProductDeleted      = "product.deleted"
+	AvailabilityChanged = "availability.changed"
+)
+
+// Причины изменения доступности
+const (
+	ReasonStopList = "stop_list" // ресторан поставил или снял товар со стоп-листа
+	ReasonStock    = "stock"     // товар распродан или порции вернулись на склад
+)
+
+// Envelope — сообщение в шине
+type Envelope = outbox.Envelope
+
+type ProductDeletedData struct {
+	ProductID int64 `json:"product_id"`
+}
+
+type AvailabilityChangedData struct {
+	ProductIDs  []int64 `json:"product_ids"`
+	IsAvailable bool    `json:"is_available"`
+	Reason      string  `json:"reason"`
+}
+
+// Execer — транзакция, в которой произошло изменение (pgx.Tx)
+type Execer = outbox.Execer
+
+// Enqueue записывает событие в outbox. Вызывать нужно в транзакции изменения:
+// откатится транзакция — пропадет и событие.
+func Enqueue(ctx context.Context, tx Execer, eventType string, data any) error {
+	return outbox.Enqueue(ctx, tx, Table, "", eventType, SchemaVersion, data)
+}
+
+// NewRelay — фоновая публикация событий каталога, запускается в cmd/catalog
+func NewRelay(db *pgxpool.Pool, url string, interval time.Duration) *outbox.Relay {
+	return outbox.NewRelay(db, url, interval, Table, Exchange)
+}
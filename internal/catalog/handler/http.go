@@ -0,0 +1,386 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/images"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/menuio"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/storage"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/httpmw"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/locale"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	catService service.Service
+	uploader   *images.Uploader
+	store      storage.Storage
+}
+
+func New(s service.Service, uploader *images.Uploader, store storage.Storage) *Handler {
+	return &Handler{catService: s, uploader: uploader, store: store}
+}
+
+// RegisterRoutes "рисует" карту ручек каталога
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	// Открытые ручки: товары могут смотреть все
+	r.Get("/products", h.List)
+	r.Get("/products/{id}", h.Get)
+	r.Get(images.URLPrefix+"*", h.ServeImage)
+	r.Get("/kitchens", h.ListKitchens)
+	r.Get("/kitchens/{id}", h.GetKitchen)
+
+	// ЗАЩИЩЕННАЯ группа: меню и кухни меняют только ресторан и администратор
+	r.Group(func(r chi.Router) {
+		r.Use(httpmw.AuthMiddleware)
+		r.Use(httpmw.RequireRole("restaurant", "admin"))
+
+		r.Post("/products", h.Create)
+		r.Put("/products/{id}", h.Update)
+		r.Patch("/products/{id}", h.Patch)
+		r.Delete("/products/{id}", h.Delete)
+		r.Post("/products/availability", h.SetAvailability) // стоп-лист
+		r.Post("/products/import", h.Import)
+		r.Get("/products/export", h.Export)
+		r.Post("/products/{id}/image", h.UploadImage)
+
+		r.Post("/kitchens", h.CreateKitchen)
+		r.Put("/kitchens/{id}", h.UpdateKitchen)
+		r.Put("/kitchens/{id}/exceptions/{date}", h.SetKitchenException)
+		r.Delete("/kitchens/{id}/exceptions/{date}", h.DeleteKitchenException)
+	})
+
+	// Переводы названий и описаний ведет только администратор
+	r.Group(func(r chi.Router) {
+		r.Use(httpmw.AuthMiddleware)
+		r.Use(httpmw.RequireRole("admin"))
+
+		r.Get("/products/{id}/translations", h.ListTranslations)
+		r.Put("/products/{id}/translations/{locale}", h.SetTranslation)
+		r.Delete("/products/{id}/translations/{locale}", h.DeleteTranslation)
+	})
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f := service.ListFilter{ExcludeAllergens: diet.ParseList(query.Get("exclude_allergens"))}
+	f.OpenNow, _ = strconv.ParseBool(query.Get("open_now"))
+	if v := query.Get("max_kcal"); v != "" {
+		kcal, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "max_kcal должен быть числом", http.StatusBadRequest)
+			return
+		}
+		f.MaxKcal = kcal
+	}
+
+	products, err := h.catService.GetAllProducts(r.Context(), f)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Язык выбирается по запросу, поэтому кэши между клиентом и нами должны это учитывать
+	w.Header().Set("Vary", "Accept-Language")
+	writeCachedJSON(w, r, pg.LocalizeAll(products, locale.FromRequest(r)))
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.catService.GetProduct(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Vary", "Accept-Language")
+	writeCachedJSON(w, r, p.Localize(locale.FromRequest(r)))
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	// Новый товар по умолчанию сразу в продаже
+	p := pg.Product{IsAvailable: true}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	id, err := h.catService.AddProduct(r.Context(), p)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"id": id})
+}
+
+// Update — PUT: полностью заменяет поля товара
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	p := pg.Product{IsAvailable: true}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	p.ID = id
+	if err := h.catService.UpdateProduct(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// Patch — меняет только переданные поля
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var patch pg.ProductPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	p, err := h.catService.PatchProduct(r.Context(), id, patch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.catService.DeleteProduct(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetAvailability — массовое включение/выключение товаров (стоп-лист ресторана)
+func (h *Handler) SetAvailability(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ProductIDs  []int64 `json:"product_ids"`
+		IsAvailable bool    `json:"is_available"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	updated, err := h.catService.SetStopList(r.Context(), input.ProductIDs, input.IsAvailable)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"updated": updated})
+}
+
+// maxImportSize — ограничение на размер файла меню
+const maxImportSize = 10 << 20
+
+// Import — массовая загрузка меню: POST /products/import?format=csv&dry_run=true
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = menuio.FormatJSON
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = menuio.FormatCSV
+		}
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	categories, items, err := menuio.Decode(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	report, err := h.catService.ImportMenu(r.Context(), categories, items, dryRun)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Частично неуспешный импорт — всё равно 200: подробности в отчете по строкам
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Export — выгрузка меню для бэкапа: GET /products/export?format=csv
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = menuio.FormatJSON
+	}
+	if format != menuio.FormatJSON && format != menuio.FormatCSV {
+		writeError(w, menuio.ErrUnknownFormat)
+		return
+	}
+
+	menu, err := h.catService.ExportMenu(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	contentType := "application/json"
+	if format == menuio.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="menu.`+format+`"`)
+	menuio.Encode(format, w, menu)
+}
+
+// UploadImage — multipart-загрузка картинки товара в поле "image"
+func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Запас на заголовки multipart сверх размера самой картинки
+	r.Body = http.MaxBytesReader(w, r.Body, h.uploader.MaxSize()+1<<20)
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "Ожидается файл в поле image (multipart/form-data)", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.uploader.MaxSize()+1))
+	if err != nil {
+		http.Error(w, "Не удалось прочитать файл", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.uploader.Upload(r.Context(), id, data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ServeImage раздает картинки. Имена содержат хеш содержимого и никогда не меняются,
+// поэтому браузеру и CDN можно хранить их год без перепроверки.
+// 304 отдаем только для файла, который еще существует: удаленную картинку кэш должен потерять.
+func (h *Handler) ServeImage(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "*")
+	f, err := h.store.Open(r.Context(), name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer f.Close()
+
+	etag := `"` + path.Base(name) + `"`
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	io.Copy(w, f)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Неверный id в пути", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeError переводит ошибки сервиса в HTTP-коды
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, pg.ErrNotFound), errors.Is(err, pg.ErrUnknownKitchen),
+		errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidName):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, images.ErrTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, images.ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, service.ErrInvalidPrice),
+		errors.Is(err, service.ErrEmptyName),
+		errors.Is(err, service.ErrNoProducts),
+		errors.Is(err, service.ErrInvalidGroup),
+		errors.Is(err, service.ErrInvalidStock),
+		errors.Is(err, service.ErrInvalidKitchen),
+		errors.Is(err, service.ErrInvalidCurrency),
+		errors.Is(err, service.ErrInvalidImport),
+		errors.Is(err, service.ErrEmptyKitchenName),
+		errors.Is(err, schedule.ErrInvalidSchedule),
+		errors.Is(err, locale.ErrInvalid),
+		errors.Is(err, diet.ErrUnknownAllergen),
+		errors.Is(err, diet.ErrInvalidNutrition),
+		errors.Is(err, tax.ErrUnknownCategory),
+		errors.Is(err, menuio.ErrUnknownFormat):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeCachedJSON отдает JSON с ETag и отвечает 304, если у клиента та же версия
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	// Клиент может хранить ответ, но обязан перепроверять его через If-None-Match
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// etagMatches разбирает If-None-Match: список тегов через запятую, "*" или слабые W/"..."
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
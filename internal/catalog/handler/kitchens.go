@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) ListKitchens(w http.ResponseWriter, r *http.Request) {
+	kitchens, err := h.catService.ListKitchens(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, kitchens)
+}
+
+func (h *Handler) GetKitchen(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	k, err := h.catService.GetKitchen(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, k)
+}
+
+// CreateKitchen — новая кухня с недельным графиком и (необязательно) исключениями
+func (h *Handler) CreateKitchen(w http.ResponseWriter, r *http.Request) {
+	var k pg.Kitchen
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	id, err := h.catService.CreateKitchen(r.Context(), k)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]int64{"id": id})
+}
+
+// UpdateKitchen — PUT: данные кухни и недельный график заменяются целиком
+func (h *Handler) UpdateKitchen(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var k pg.Kitchen
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	k.ID = id
+	if err := h.catService.UpdateKitchen(r.Context(), k); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetKitchenException — праздник или особый график на дату: PUT /kitchens/{id}/exceptions/2026-12-31
+func (h *Handler) SetKitchenException(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var e schedule.Exception
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	e.Date = chi.URLParam(r, "date")
+	if err := h.catService.SetKitchenException(r.Context(), id, e); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteKitchenException(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.catService.DeleteKitchenException(r.Context(), id, chi.URLParam(r, "date")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListTranslations отдает все переводы товара: GET /products/{id}/translations
+func (h *Handler) ListTranslations(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.catService.GetProduct(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	translations := p.Translations
+	if translations == nil {
+		translations = map[string]pg.Translation{}
+	}
+	writeJSON(w, http.StatusOK, translations)
+}
+
+// SetTranslation — перевод на язык: PUT /products/{id}/translations/en {"name": "...", "description": "..."}
+func (h *Handler) SetTranslation(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var t pg.Translation
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+	if err := h.catService.SetTranslation(r.Context(), id, chi.URLParam(r, "locale"), t); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.catService.DeleteTranslation(r.Context(), id, chi.URLParam(r, "locale")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
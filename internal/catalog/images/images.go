@@ -0,0 +1,192 @@
+// Package images принимает картинки товаров: проверяет тип и размер,
+// сохраняет оригинал в storage и нарезает уменьшенные копии (превью).
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/storage"
+)
+
+var (
+	ErrUnsupportedType = errors.New("поддерживаются только картинки JPEG и PNG")
+	ErrTooLarge        = errors.New("картинка слишком большая")
+)
+
+// URLPrefix — по этому пути каталог раздает сохраненные картинки
+const URLPrefix = "/images/"
+
+// ThumbnailWidths — ширины превью в пикселях (высота — по пропорциям)
+var ThumbnailWidths = []int{200, 600}
+
+// maxPixels защищает от "бомб": маленький файл, который раскрывается в гигантскую картинку
+const maxPixels = 40_000_000
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// Result — ссылки на оригинал и превью после загрузки
+type Result struct {
+	Product    pg.Product        `json:"product"`
+	Thumbnails map[string]string `json:"thumbnails"` // ширина -> URL
+}
+
+type Uploader struct {
+	store      storage.Storage
+	catService service.Service
+	maxSize    int64
+}
+
+func New(store storage.Storage, catService service.Service, maxSize int64) *Uploader {
+	return &Uploader{store: store, catService: catService, maxSize: maxSize}
+}
+
+// MaxSize — лимит размера файла в байтах, хендлер ограничивает по нему тело запроса
+func (u *Uploader) MaxSize() int64 {
+	return u.maxSize
+}
+
+// Upload сохраняет картинку товара и прописывает её URL в image_url.
+// Имя файла — хеш содержимого, поэтому файлы можно кэшировать "навсегда".
+func (u *Uploader) Upload(ctx context.Context, productID int64, data []byte) (Result, error) {
+	if int64(len(data)) > u.maxSize {
+		return Result{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return Result{}, ErrUnsupportedType
+	}
+
+	// Сначала читаем только размеры, чтобы не декодировать огромную картинку
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Result{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+
+	// Товар должен существовать до того, как мы что-то запишем на диск
+	before, err := u.catService.GetProduct(ctx, productID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	base := fmt.Sprintf("products/%d/%s", productID, hex.EncodeToString(sum[:12]))
+
+	if err := u.store.Save(ctx, base+ext, bytes.NewReader(data)); err != nil {
+		return Result{}, err
+	}
+
+	thumbnails := make(map[string]string, len(ThumbnailWidths))
+	for _, width := range ThumbnailWidths {
+		var buf bytes.Buffer
+		thumb := resize(img, width)
+		if ext == ".png" {
+			err = png.Encode(&buf, thumb)
+		} else {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		name := ThumbnailName(base+ext, width)
+		if err := u.store.Save(ctx, name, &buf); err != nil {
+			return Result{}, err
+		}
+		thumbnails[strconv.Itoa(width)] = URLPrefix + name
+	}
+
+	url := URLPrefix + base + ext
+	p, err := u.catService.PatchProduct(ctx, productID, pg.ProductPatch{ImageURL: &url})
+	if err != nil {
+		return Result{}, err
+	}
+	// Ссылка уже указывает на новую картинку — старую вместе с превью можно удалить
+	if before.ImageURL != url {
+		u.deleteImage(ctx, before.ImageURL)
+	}
+	return Result{Product: p, Thumbnails: thumbnails}, nil
+}
+
+// deleteImage удаляет из хранилища оригинал и превью по URL из image_url.
+// Внешние ссылки не трогаем, а ошибки только логируем: товар уже обновлен.
+func (u *Uploader) deleteImage(ctx context.Context, url string) {
+	original, ok := strings.CutPrefix(url, URLPrefix)
+	if !ok || original == "" {
+		return
+	}
+	names := []string{original}
+	for _, width := range ThumbnailWidths {
+		names = append(names, ThumbnailName(original, width))
+	}
+	for _, name := range names {
+		if err := u.store.Delete(ctx, name); err != nil {
+			log.Printf("images: не удалось удалить %s: %v", name, err)
+		}
+	}
+}
+
+// ThumbnailName — имя превью для оригинала: "products/1/abc.jpg" -> "products/1/abc_200.jpg"
+func ThumbnailName(original string, width int) string {
+	ext := path.Ext(original)
+	return strings.TrimSuffix(original, ext) + "_" + strconv.Itoa(width) + ext
+}
+
+// resize уменьшает картинку до ширины width усреднением пикселей (box filter).
+// Увеличивать не пытаемся: маленькая картинка возвращается как есть.
+func resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	if b.Dx() <= width {
+		return src
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(b.Min.X+(x+1)*b.Dx()/width, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,257 @@
+// Package menuio читает и пишет меню в CSV и JSON для массового импорта/экспорта.
+package menuio
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// noAllergens в колонке allergens — "аллергенов нет"; пустая ячейка — "состав не указан"
+const noAllergens = "none"
+
+var ErrUnknownFormat = errors.New("неизвестный формат: поддерживаются csv и json")
+
+// csvHeader — колонки CSV. option_groups — JSON-массив групп опций в одной ячейке.
+var csvHeader = []string{"sku", "name", "description", "price", "category", "image_url", "is_available", "option_groups",
+	"kitchen_id", "available_from", "available_until", "stock",
+	"allergens", "kcal", "protein", "fat", "carbs", "tax_category"}
+
+// Decode разбирает файл меню. Ошибка возвращается, только если файл нельзя прочитать целиком;
+// ошибки отдельных строк кладутся в ImportItem.Err, чтобы попасть в отчет.
+func Decode(format string, r io.Reader) ([]pg.Category, []service.ImportItem, error) {
+	switch format {
+	case FormatJSON:
+		return decodeJSON(r)
+	case FormatCSV:
+		items, err := decodeCSV(r)
+		return nil, items, err
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+// Encode пишет меню в выбранном формате. В CSV категории попадают только как колонка товара.
+func Encode(format string, w io.Writer, menu service.Menu) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(menu)
+	case FormatCSV:
+		return encodeCSV(w, menu.Products)
+	}
+	return ErrUnknownFormat
+}
+
+// decodeJSON принимает формат экспорта {"categories": [...], "products": [...]}
+// или просто массив товаров
+func decodeJSON(r io.Reader) ([]pg.Category, []service.ImportItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw struct {
+		Categories []pg.Category     `json:"categories"`
+		Products   []json.RawMessage `json:"products"`
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &raw.Products)
+	} else {
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", service.ErrInvalidImport, err)
+	}
+
+	// Каждый товар разбираем отдельно: битая строка не должна ронять весь импорт
+	items := make([]service.ImportItem, len(raw.Products))
+	for i, msg := range raw.Products {
+		items[i] = service.ImportItem{Row: i + 1, Product: pg.Product{IsAvailable: true}}
+		if err := json.Unmarshal(msg, &items[i].Product); err != nil {
+			items[i].Err = err
+		}
+	}
+	return raw.Categories, items, nil
+}
+
+func decodeCSV(r io.Reader) ([]service.ImportItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // строка с недостающими колонками не должна ломать весь файл
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: нет строки заголовка: %v", service.ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: нет колонки %q", service.ErrInvalidImport, required)
+		}
+	}
+
+	var items []service.ImportItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: строка %d: %v", service.ErrInvalidImport, line, err)
+		}
+		p, err := parseCSVRecord(record, columns)
+		items = append(items, service.ImportItem{Row: line, Product: p, Err: err})
+	}
+	return items, nil
+}
+
+func parseCSVRecord(record []string, columns map[string]int) (pg.Product, error) {
+	get := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	p := pg.Product{
+		SKU:            get("sku"),
+		Name:           get("name"),
+		Description:    get("description"),
+		Category:       get("category"),
+		ImageURL:       get("image_url"),
+		IsAvailable:    true,
+		AvailableFrom:  get("available_from"),
+		AvailableUntil: get("available_until"),
+		TaxCategory:    tax.Category(get("tax_category")),
+	}
+
+	price, err := money.Parse(get("price"))
+	if err != nil {
+		return p, fmt.Errorf("цена: %v", err)
+	}
+	p.Price = price
+
+	if v := get("is_available"); v != "" {
+		if p.IsAvailable, err = strconv.ParseBool(v); err != nil {
+			return p, fmt.Errorf("is_available: ожидается true/false, получено %q", v)
+		}
+	}
+	if v := get("kitchen_id"); v != "" {
+		if p.KitchenID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return p, fmt.Errorf("kitchen_id: ожидается число, получено %q", v)
+		}
+	}
+	if v := get("stock"); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("stock: ожидается число, получено %q", v)
+		}
+		p.Stock = &stock
+	}
+	switch v := get("allergens"); v {
+	case "":
+		// состав не указан
+	case noAllergens:
+		p.Allergens = []string{}
+	default:
+		p.Allergens = diet.ParseList(v)
+	}
+	if get("kcal") != "" {
+		var n diet.Nutrition
+		for _, field := range []struct {
+			name string
+			dst  *float64
+		}{{"kcal", &n.Kcal}, {"protein", &n.Protein}, {"fat", &n.Fat}, {"carbs", &n.Carbs}} {
+			v := get(field.name)
+			if v == "" {
+				continue
+			}
+			if *field.dst, err = strconv.ParseFloat(v, 64); err != nil {
+				return p, fmt.Errorf("%s: ожидается число, получено %q", field.name, v)
+			}
+		}
+		p.Nutrition = &n
+	}
+	// Без колонки option_groups опции товара не трогаем; пустая ячейка в колонке — снять все опции
+	if _, ok := columns["option_groups"]; ok {
+		p.OptionGroups = []pg.OptionGroup{}
+		if v := get("option_groups"); v != "" {
+			if err := json.Unmarshal([]byte(v), &p.OptionGroups); err != nil {
+				return p, fmt.Errorf("option_groups: %v", err)
+			}
+		}
+	}
+	return p, nil
+}
+
+func encodeCSV(w io.Writer, products []pg.Product) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, p := range products {
+		options := ""
+		if len(p.OptionGroups) > 0 {
+			data, err := json.Marshal(p.OptionGroups)
+			if err != nil {
+				return err
+			}
+			options = string(data)
+		}
+		kitchen := ""
+		if p.KitchenID != 0 {
+			kitchen = strconv.FormatInt(p.KitchenID, 10)
+		}
+		stock := ""
+		if p.Stock != nil {
+			stock = strconv.Itoa(*p.Stock)
+		}
+		var kcal, protein, fat, carbs string
+		if n := p.Nutrition; n != nil {
+			kcal, protein, fat, carbs = formatFloat(n.Kcal), formatFloat(n.Protein), formatFloat(n.Fat), formatFloat(n.Carbs)
+		}
+		record := []string{
+			p.SKU, p.Name, p.Description, p.Price.String(), p.Category, p.ImageURL,
+			strconv.FormatBool(p.IsAvailable), options,
+			kitchen, p.AvailableFrom, p.AvailableUntil, stock,
+			formatAllergens(p.Allergens), kcal, protein, fat, carbs, string(p.TaxCategory),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatAllergens(allergens []string) string {
+	if allergens != nil && len(allergens) == 0 {
+		return noAllergens
+	}
+	return strings.Join(allergens, ",")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
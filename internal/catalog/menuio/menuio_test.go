@@ -0,0 +1,149 @@
+package menuio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/service"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+func decodeOne(t *testing.T, format, in string) pg.Product {
+	t.Helper()
+	_, items, err := Decode(format, strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Decode() вернул %d строк, ожидали 1", len(items))
+	}
+	if items[0].Err != nil {
+		t.Fatalf("строка %d: %v", items[0].Row, items[0].Err)
+	}
+	return items[0].Product
+}
+
+func TestDecodeCSVOptionGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []pg.OptionGroup
+	}{
+		{"нет колонки — опции не трогаем", "sku,name,price\nB-1,Борщ,350\n", nil},
+		{"пустая ячейка — снять опции", "sku,name,price,option_groups\nB-1,Борщ,350,\n", []pg.OptionGroup{}},
+		{
+			"группы в ячейке",
+			"sku,name,price,option_groups\nB-1,Борщ,350,\"[{\"\"name\"\":\"\"Сметана\"\",\"\"max_select\"\":1,\"\"options\"\":[{\"\"name\"\":\"\"Двойная\"\",\"\"price_delta\"\":\"\"50\"\"}]}]\"\n",
+			[]pg.OptionGroup{{Name: "Сметана", MaxSelect: 1, Options: []pg.Option{{Name: "Двойная", PriceDelta: money.FromMajor(50)}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := decodeOne(t, FormatCSV, tt.in)
+			if !reflect.DeepEqual(p.OptionGroups, tt.want) {
+				t.Fatalf("OptionGroups = %#v, ожидали %#v", p.OptionGroups, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONOptionGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		present bool
+		groups  int
+	}{
+		{"нет поля", `[{"sku":"B-1","name":"Борщ","price":"350"}]`, false, 0},
+		{"null", `[{"sku":"B-1","name":"Борщ","price":"350","option_groups":null}]`, false, 0},
+		{"пустой список", `[{"sku":"B-1","name":"Борщ","price":"350","option_groups":[]}]`, true, 0},
+		{"одна группа", `{"products":[{"sku":"B-1","name":"Борщ","price":"350","option_groups":[{"name":"Сметана","max_select":1,"options":[{"name":"Да"}]}]}]}`, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := decodeOne(t, FormatJSON, tt.in)
+			if (p.OptionGroups != nil) != tt.present || len(p.OptionGroups) != tt.groups {
+				t.Fatalf("OptionGroups = %#v", p.OptionGroups)
+			}
+		})
+	}
+}
+
+func TestDecodeCSVFields(t *testing.T) {
+	in := "SKU,Name,Price,is_available,kitchen_id,stock,allergens,kcal,fat\n" +
+		"B-1,Борщ,350.5,false,3,10,none,120.5,4\n"
+	p := decodeOne(t, FormatCSV, in)
+	if p.SKU != "B-1" || p.Price != money.FromMinor(35050) || p.IsAvailable || p.KitchenID != 3 {
+		t.Fatalf("разобрали %+v", p)
+	}
+	if p.Stock == nil || *p.Stock != 10 {
+		t.Fatalf("Stock = %v", p.Stock)
+	}
+	if p.Allergens == nil || len(p.Allergens) != 0 {
+		t.Fatalf("none должно значить «аллергенов нет», получили %#v", p.Allergens)
+	}
+	if p.Nutrition == nil || *p.Nutrition != (diet.Nutrition{Kcal: 120.5, Fat: 4}) {
+		t.Fatalf("Nutrition = %+v", p.Nutrition)
+	}
+}
+
+func TestDecodeCSVRowErrors(t *testing.T) {
+	in := "sku,name,price,stock,option_groups\n" +
+		"A,Суп,abc,,\n" +
+		"B,Суп,100,много,\n" +
+		"C,Суп,100,,{не json\n" +
+		"D,Суп,100,,\n"
+	_, items, err := Decode(FormatCSV, strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	for i, wantErr := range []bool{true, true, true, false} {
+		if (items[i].Err != nil) != wantErr {
+			t.Errorf("строка %d: ошибка = %v", items[i].Row, items[i].Err)
+		}
+		if items[i].Row != i+2 {
+			t.Errorf("Row = %d, ожидали %d", items[i].Row, i+2)
+		}
+	}
+}
+
+func TestDecodeInvalidFile(t *testing.T) {
+	for _, tt := range []struct{ format, in string }{
+		{FormatCSV, "sku,name\nB-1,Борщ\n"},
+		{FormatCSV, ""},
+		{FormatJSON, "{не json"},
+	} {
+		if _, _, err := Decode(tt.format, strings.NewReader(tt.in)); !errors.Is(err, service.ErrInvalidImport) {
+			t.Errorf("Decode(%s, %q) = %v, ожидали ErrInvalidImport", tt.format, tt.in, err)
+		}
+	}
+	if _, _, err := Decode("xml", strings.NewReader("")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Decode(xml) = %v", err)
+	}
+	if err := Encode("xml", &bytes.Buffer{}, service.Menu{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Encode(xml) = %v", err)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	stock := 5
+	in := pg.Product{
+		SKU: "B-1", Name: "Борщ", Description: "с салом, без лука", Price: money.FromMajor(350),
+		Category: "Супы", IsAvailable: true, KitchenID: 2, AvailableFrom: "08:00", AvailableUntil: "11:00",
+		Stock: &stock, Allergens: []string{}, Nutrition: &diet.Nutrition{Kcal: 250, Protein: 8.5},
+		OptionGroups: []pg.OptionGroup{{Name: "Сметана", MaxSelect: 1, Options: []pg.Option{{Name: "Двойная", PriceDelta: money.FromMajor(50)}}}},
+	}
+	var buf bytes.Buffer
+	if err := Encode(FormatCSV, &buf, service.Menu{Products: []pg.Product{in}}); err != nil {
+		t.Fatalf("Encode() = %v", err)
+	}
+	out := decodeOne(t, FormatCSV, buf.String())
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("после экспорта и импорта:\n%+v\nожидали\n%+v", out, in)
+	}
+}
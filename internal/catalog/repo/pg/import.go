@@ -0,0 +1,182 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/jackc/pgx/v5"
+)
+
+// Category — раздел меню ("Пицца", "Напитки")
+type Category struct {
+	ID       int64  `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// UpsertResult — что импорт сделал с конкретным товаром
+type UpsertResult struct {
+	ID      int64
+	Created bool // false — товар с таким sku уже был и его обновили
+}
+
+// ensureCategory находит категорию по названию или создает её.
+// Пустое название — товар без категории (NULL).
+func ensureCategory(ctx context.Context, tx pgx.Tx, name string) (*int64, error) {
+	if name == "" {
+		return nil, nil
+	}
+	var id int64
+	// DO UPDATE (а не DO NOTHING), чтобы RETURNING вернул id и для существующей строки
+	query := "INSERT INTO categories (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id"
+	if err := tx.QueryRow(ctx, query, name).Scan(&id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// UpsertBySKU сохраняет меню одной транзакцией: категории, затем товары по sku.
+// Удаленный ранее товар с тем же sku "воскресает".
+func (r *pgRepo) UpsertBySKU(ctx context.Context, categories []Category, products []Product) ([]UpsertResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, c := range categories {
+		query := "INSERT INTO categories (name, position) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET position = EXCLUDED.position"
+		if _, err := tx.Exec(ctx, query, c.Name, c.Position); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]UpsertResult, 0, len(products))
+	for _, p := range products {
+		categoryID, err := ensureCategory(ctx, tx, p.Category)
+		if err != nil {
+			return nil, err
+		}
+
+		// Состояние до импорта нужно для событий; удаленный товар для подписчиков не существует
+		var before availabilityState
+		existed := true
+		query := "SELECT is_available AND deleted_at IS NULL, COALESCE(stock, 1) > 0, deleted_at IS NULL FROM products WHERE sku = $1 FOR UPDATE"
+		err = tx.QueryRow(ctx, query, p.SKU).Scan(&before.listed, &before.inStock, &existed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			existed = false
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Остаток импорт задает только новым товарам: у существующих его уже уменьшили заказы.
+		// xmax = 0 только у только что вставленной строки — так отличаем создание от обновления
+		query = `
+			INSERT INTO products (sku, name, description, price, image_url, is_available, category_id,
+				kitchen_id, available_from, available_until, stock, allergens, kcal, protein, fat, carbs, tax_category)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, '')::time, NULLIF($10, '')::time, $11,
+				$12, $13, $14, $15, $16, $17)
+			ON CONFLICT (sku) DO UPDATE SET
+				name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				price = EXCLUDED.price,
+				image_url = EXCLUDED.image_url,
+				is_available = EXCLUDED.is_available,
+				category_id = EXCLUDED.category_id,
+				kitchen_id = EXCLUDED.kitchen_id,
+				available_from = EXCLUDED.available_from,
+				available_until = EXCLUDED.available_until,
+				allergens = EXCLUDED.allergens,
+				kcal = EXCLUDED.kcal,
+				protein = EXCLUDED.protein,
+				fat = EXCLUDED.fat,
+				carbs = EXCLUDED.carbs,
+				tax_category = EXCLUDED.tax_category,
+				deleted_at = NULL,
+				updated_at = NOW()
+			RETURNING id, (xmax = 0)`
+		var res UpsertResult
+		kcal, protein, fat, carbs := nutritionArgs(p.Nutrition)
+		err = tx.QueryRow(ctx, query, p.SKU, p.Name, p.Description, p.Price, p.ImageURL, p.IsAvailable, categoryID,
+			p.KitchenID, p.AvailableFrom, p.AvailableUntil, p.Stock, p.Allergens, kcal, protein, fat, carbs, p.TaxCategory).Scan(&res.ID, &res.Created)
+		if err != nil {
+			return nil, mapWriteError(err)
+		}
+		// Опции заменяем, только если они есть в файле: nil — поля нет, пустой список — снять все опции
+		if p.OptionGroups != nil {
+			if err := replaceOptionGroups(ctx, tx, res.ID, p.OptionGroups); err != nil {
+				return nil, err
+			}
+		}
+		// Переводы заменяем, только если они есть в файле: CSV их не содержит
+		if p.Translations != nil {
+			if err := replaceTranslations(ctx, tx, res.ID, p.Translations); err != nil {
+				return nil, err
+			}
+		}
+		saved, err := loadProduct(ctx, tx, res.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existed {
+			err = enqueueProduct(ctx, tx, events.ProductUpdated, saved)
+			if err == nil {
+				err = enqueueAvailability(ctx, tx, res.ID, before, stateOf(saved))
+			}
+		} else {
+			err = enqueueProduct(ctx, tx, events.ProductCreated, saved)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	return results, tx.Commit(ctx)
+}
+
+// FindIDsBySKU нужен для dry-run: показать, какие строки создадут товар, а какие обновят
+func (r *pgRepo) FindIDsBySKU(ctx context.Context, skus []string) (map[string]int64, error) {
+	rows, err := r.db.Query(ctx, "SELECT sku, id FROM products WHERE sku = ANY($1)", skus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var sku string
+		var id int64
+		if err := rows.Scan(&sku, &id); err != nil {
+			return nil, err
+		}
+		ids[sku] = id
+	}
+	return ids, rows.Err()
+}
+
+func (r *pgRepo) ListCategories(ctx context.Context) ([]Category, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, name, position FROM categories ORDER BY position, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.Position); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
@@ -0,0 +1,195 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/jackc/pgx/v5"
+)
+
+// Kitchen — кухня ресторана с координатами и расписанием работы
+type Kitchen struct {
+	ID   int64   `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	schedule.Schedule
+
+	OpenNow bool `json:"open_now"` // вычисляется сервисом, в базе не хранится
+}
+
+type KitchenRepository interface {
+	CreateKitchen(ctx context.Context, k Kitchen) (int64, error)
+	ListKitchens(ctx context.Context) ([]Kitchen, error)
+	GetKitchen(ctx context.Context, id int64) (Kitchen, error)
+	// ExistingKitchens возвращает те из переданных id, для которых кухня есть в базе
+	ExistingKitchens(ctx context.Context, ids []int64) (map[int64]bool, error)
+	// UpdateKitchen меняет данные кухни и целиком заменяет недельный график
+	UpdateKitchen(ctx context.Context, k Kitchen) error
+	SetKitchenException(ctx context.Context, kitchenID int64, e schedule.Exception) error
+	DeleteKitchenException(ctx context.Context, kitchenID int64, date string) error
+}
+
+func (r *pgRepo) CreateKitchen(ctx context.Context, k Kitchen) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	query := "INSERT INTO kitchens (name, timezone, lat, lon) VALUES ($1, $2, $3, $4) RETURNING id"
+	if err := tx.QueryRow(ctx, query, k.Name, k.Timezone, k.Lat, k.Lon).Scan(&id); err != nil {
+		return 0, err
+	}
+	if err := replaceHours(ctx, tx, id, k.Hours); err != nil {
+		return 0, err
+	}
+	for _, e := range k.Exceptions {
+		if err := upsertException(ctx, tx, id, e); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (r *pgRepo) ListKitchens(ctx context.Context) ([]Kitchen, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, name, COALESCE(lat, 0), COALESCE(lon, 0) FROM kitchens ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var kitchens []Kitchen
+	for rows.Next() {
+		var k Kitchen
+		if err := rows.Scan(&k.ID, &k.Name, &k.Lat, &k.Lon); err != nil {
+			return nil, err
+		}
+		kitchens = append(kitchens, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return kitchens, r.attachSchedules(ctx, kitchens)
+}
+
+func (r *pgRepo) ExistingKitchens(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	rows, err := r.db.Query(ctx, "SELECT id FROM kitchens WHERE id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		found[id] = true
+	}
+	return found, rows.Err()
+}
+
+func (r *pgRepo) GetKitchen(ctx context.Context, id int64) (Kitchen, error) {
+	var k Kitchen
+	query := "SELECT id, name, COALESCE(lat, 0), COALESCE(lon, 0) FROM kitchens WHERE id = $1"
+	err := r.db.QueryRow(ctx, query, id).Scan(&k.ID, &k.Name, &k.Lat, &k.Lon)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Kitchen{}, ErrUnknownKitchen
+	}
+	if err != nil {
+		return Kitchen{}, err
+	}
+	kitchens := []Kitchen{k}
+	if err := r.attachSchedules(ctx, kitchens); err != nil {
+		return Kitchen{}, err
+	}
+	return kitchens[0], nil
+}
+
+func (r *pgRepo) UpdateKitchen(ctx context.Context, k Kitchen) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := "UPDATE kitchens SET name = $2, timezone = $3, lat = $4, lon = $5 WHERE id = $1"
+	result, err := tx.Exec(ctx, query, k.ID, k.Name, k.Timezone, k.Lat, k.Lon)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUnknownKitchen
+	}
+	if err := replaceHours(ctx, tx, k.ID, k.Hours); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) SetKitchenException(ctx context.Context, kitchenID int64, e schedule.Exception) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := upsertException(ctx, tx, kitchenID, e); err != nil {
+		return mapWriteError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) DeleteKitchenException(ctx context.Context, kitchenID int64, date string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM kitchen_exceptions WHERE kitchen_id = $1 AND date = $2::date", kitchenID, date)
+	return err
+}
+
+func (r *pgRepo) attachSchedules(ctx context.Context, kitchens []Kitchen) error {
+	if len(kitchens) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(kitchens))
+	for i, k := range kitchens {
+		ids[i] = k.ID
+	}
+	schedules, err := schedule.Load(ctx, r.db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range kitchens {
+		kitchens[i].Schedule = schedules[kitchens[i].ID]
+	}
+	return nil
+}
+
+func replaceHours(ctx context.Context, tx pgx.Tx, kitchenID int64, hours []schedule.Hours) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM kitchen_hours WHERE kitchen_id = $1", kitchenID); err != nil {
+		return err
+	}
+	for _, h := range hours {
+		_, err := tx.Exec(ctx, "INSERT INTO kitchen_hours (kitchen_id, weekday, opens_at, closes_at) VALUES ($1, $2, $3::time, $4::time)",
+			kitchenID, h.Weekday, h.Opens, h.Closes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertException(ctx context.Context, tx pgx.Tx, kitchenID int64, e schedule.Exception) error {
+	query := `
+		INSERT INTO kitchen_exceptions (kitchen_id, date, is_closed, opens_at, closes_at, note)
+		VALUES ($1, $2::date, $3, NULLIF($4, '')::time, NULLIF($5, '')::time, NULLIF($6, ''))
+		ON CONFLICT (kitchen_id, date) DO UPDATE SET
+			is_closed = EXCLUDED.is_closed,
+			opens_at = EXCLUDED.opens_at,
+			closes_at = EXCLUDED.closes_at,
+			note = EXCLUDED.note`
+	_, err := tx.Exec(ctx, query, kitchenID, e.Date, e.Closed, e.Opens, e.Closes, e.Note)
+	return err
+}
@@ -0,0 +1,109 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// OptionGroup — группа опций товара ("Размер", "Соусы").
+// MinSelect/MaxSelect задают, сколько опций клиент обязан/может выбрать.
+type OptionGroup struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	MinSelect int      `json:"min_select"`
+	MaxSelect int      `json:"max_select"`
+	Options   []Option `json:"options"`
+}
+
+// Option — конкретный вариант внутри группы с надбавкой к цене товара
+type Option struct {
+	ID         int64       `json:"id"`
+	Name       string      `json:"name"`
+	PriceDelta money.Money `json:"price_delta"`
+}
+
+// querier — общее у пула и транзакции, чтобы читать опции и там, и там
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// loadOptionGroups достает группы с опциями для набора товаров одним запросом
+func loadOptionGroups(ctx context.Context, q querier, productIDs []int64) (map[int64][]OptionGroup, error) {
+	query := `
+		SELECT g.product_id, g.id, g.name, g.min_select, g.max_select, o.id, o.name, o.price_delta
+		FROM product_option_groups g
+		JOIN product_options o ON o.group_id = g.id
+		WHERE g.product_id = ANY($1)
+		ORDER BY g.product_id, g.position, g.id, o.position, o.id`
+	rows, err := q.Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]OptionGroup)
+	for rows.Next() {
+		var productID int64
+		var g OptionGroup
+		var o Option
+		if err := rows.Scan(&productID, &g.ID, &g.Name, &g.MinSelect, &g.MaxSelect, &o.ID, &o.Name, &o.PriceDelta); err != nil {
+			return nil, err
+		}
+		groups := result[productID]
+		// Строки отсортированы, поэтому новая группа начинается, когда меняется её id
+		if len(groups) == 0 || groups[len(groups)-1].ID != g.ID {
+			groups = append(groups, g)
+		}
+		last := &groups[len(groups)-1]
+		last.Options = append(last.Options, o)
+		result[productID] = groups
+	}
+	return result, rows.Err()
+}
+
+// replaceOptionGroups полностью пересобирает опции товара внутри транзакции
+func replaceOptionGroups(ctx context.Context, tx pgx.Tx, productID int64, groups []OptionGroup) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM product_option_groups WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+
+	for gi, g := range groups {
+		var groupID int64
+		err := tx.QueryRow(ctx,
+			"INSERT INTO product_option_groups (product_id, name, min_select, max_select, position) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			productID, g.Name, g.MinSelect, g.MaxSelect, gi).Scan(&groupID)
+		if err != nil {
+			return err
+		}
+		for oi, o := range g.Options {
+			_, err = tx.Exec(ctx,
+				"INSERT INTO product_options (group_id, name, price_delta, position) VALUES ($1, $2, $3, $4)",
+				groupID, o.Name, o.PriceDelta, oi)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attachOptionGroups дописывает группы опций к уже загруженным товарам
+func attachOptionGroups(ctx context.Context, q querier, products []Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	groups, err := loadOptionGroups(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].OptionGroups = groups[products[i].ID]
+	}
+	return nil
+}
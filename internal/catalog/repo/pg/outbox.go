@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/jackc/pgx/v5"
+)
+
+// productEvent — data событий product.created / product.updated
+type productEvent struct {
+	Product Product `json:"product"`
+}
+
+// availabilityState — можно ли заказать товар: не в стоп-листе и не распродан
+type availabilityState struct {
+	listed  bool // is_available
+	inStock bool
+}
+
+func (s availabilityState) orderable() bool { return s.listed && s.inStock }
+
+func stateOf(p Product) availabilityState {
+	return availabilityState{listed: p.IsAvailable, inStock: !p.SoldOut}
+}
+
+// lockAvailability запоминает доступность товара до изменения и блокирует строку до конца транзакции,
+// чтобы сравнить "до" и "после" без гонок
+func lockAvailability(ctx context.Context, tx pgx.Tx, id int64) (availabilityState, error) {
+	var s availabilityState
+	query := "SELECT is_available, COALESCE(stock, 1) > 0 FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"
+	err := tx.QueryRow(ctx, query, id).Scan(&s.listed, &s.inStock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+// enqueueAvailability публикует availability.changed, если товар стал (не)доступен для заказа
+func enqueueAvailability(ctx context.Context, tx pgx.Tx, id int64, before, after availabilityState) error {
+	if before.orderable() == after.orderable() {
+		return nil
+	}
+	reason := events.ReasonStock
+	if before.listed != after.listed {
+		reason = events.ReasonStopList
+	}
+	return events.Enqueue(ctx, tx, events.AvailabilityChanged, events.AvailabilityChangedData{
+		ProductIDs: []int64{id}, IsAvailable: after.orderable(), Reason: reason,
+	})
+}
+
+// enqueueProduct кладет в outbox товар целиком — так, как он будет виден после коммита
+func enqueueProduct(ctx context.Context, tx pgx.Tx, eventType string, p Product) error {
+	return events.Enqueue(ctx, tx, eventType, productEvent{Product: p})
+}
+
+// loadProduct перечитывает товар со всеми опциями и переводами внутри транзакции
+func loadProduct(ctx context.Context, tx pgx.Tx, id int64) (Product, error) {
+	p, err := scanProduct(tx.QueryRow(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id))
+	if err != nil {
+		return Product{}, err
+	}
+	products := []Product{p}
+	if err := attachOptionGroups(ctx, tx, products); err != nil {
+		return Product{}, err
+	}
+	if err := attachTranslations(ctx, tx, products); err != nil {
+		return Product{}, err
+	}
+	return products[0], nil
+}
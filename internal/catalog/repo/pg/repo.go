@@ -2,21 +2,96 @@ package pg
 
 import (
 	"context"
+	"errors"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	// ErrNotFound — товара нет или он удален (мягко)
+	ErrNotFound = errors.New("товар не найден")
+	// ErrUnknownKitchen — товар ссылается на несуществующую кухню
+	ErrUnknownKitchen = errors.New("кухня не найдена")
+)
+
 type Product struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	ImageURL    string  `json:"image_url"`
+	ID          int64       `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	ImageURL    string      `json:"image_url"`
+	IsAvailable bool        `json:"is_available"`       // false — товар в стоп-листе
+	SKU         string      `json:"sku,omitempty"`      // внешний артикул ресторана
+	Category    string      `json:"category,omitempty"` // название категории меню
+	// Ставка НДС для чека; при записи пустая — tax.Default
+	TaxCategory tax.Category `json:"tax_category"`
+
+	// Кухня, которая готовит товар, и окно подачи по её местному времени ("08:00"–"11:00")
+	KitchenID      int64  `json:"kitchen_id,omitempty"`
+	AvailableFrom  string `json:"available_from,omitempty"`
+	AvailableUntil string `json:"available_until,omitempty"`
+
+	// Остаток порций (nil — без ограничения). Заказы резервируют порции,
+	// при нуле товар помечается sold_out и заказать его нельзя.
+	Stock   *int `json:"stock"`
+	SoldOut bool `json:"sold_out"` // вычисляется из stock, при записи игнорируется
+
+	// Аллергены из справочника diet (nil — состав не указан, [] — аллергенов нет) и КБЖУ порции
+	Allergens []string        `json:"allergens"`
+	Nutrition *diet.Nutrition `json:"nutrition,omitempty"`
+
+	OptionGroups []OptionGroup `json:"option_groups"`
+	// Переводы по языкам ("en" -> ...); витрина получает уже выбранный язык, см. Localize
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+// ProductPatch — частичное обновление товара: nil означает "поле не меняем"
+type ProductPatch struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Price       *money.Money  `json:"price"`
+	ImageURL    *string       `json:"image_url"`
+	IsAvailable *bool         `json:"is_available"`
+	SKU         *string       `json:"sku"`
+	Category    *string       `json:"category"` // "" — убрать товар из категории
+	TaxCategory *tax.Category `json:"tax_category"`
+
+	// 0 / "" — снять привязку к кухне / ограничение окна
+	KitchenID      *int64  `json:"kitchen_id"`
+	AvailableFrom  *string `json:"available_from"`
+	AvailableUntil *string `json:"available_until"`
+	// -1 — снять ограничение остатка
+	Stock     *int            `json:"stock"`
+	Allergens *[]string       `json:"allergens"`
+	Nutrition *diet.Nutrition `json:"nutrition"`
+
+	// Если передано — опции товара пересобираются целиком
+	OptionGroups *[]OptionGroup `json:"option_groups"`
 }
 
 type Repository interface {
 	Create(ctx context.Context, p Product) (int64, error)
 	List(ctx context.Context) ([]Product, error)
+	GetByID(ctx context.Context, id int64) (Product, error)
+	Update(ctx context.Context, p Product) error
+	Patch(ctx context.Context, id int64, patch ProductPatch) (Product, error)
+	Delete(ctx context.Context, id int64) error
+	// SetAvailability массово ставит/снимает товары со стоп-листа и возвращает число измененных строк
+	SetAvailability(ctx context.Context, ids []int64, available bool) (int64, error)
+
+	// Массовый импорт меню (см. import.go)
+	UpsertBySKU(ctx context.Context, categories []Category, products []Product) ([]UpsertResult, error)
+	FindIDsBySKU(ctx context.Context, skus []string) (map[string]int64, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+
+	KitchenRepository
+	TranslationRepository
 }
 
 type pgRepo struct {
@@ -27,15 +102,109 @@ func New(db *pgxpool.Pool) Repository {
 	return &pgRepo{db: db}
 }
 
+// description, image_url, sku и категория в базе nullable, поэтому подставляем пустую строку.
+// Категорию берем подзапросом, чтобы те же колонки работали и в RETURNING.
+const productColumns = `id, name, COALESCE(description, ''), price, COALESCE(image_url, ''), is_available,
+	COALESCE(sku, ''), COALESCE((SELECT c.name FROM categories c WHERE c.id = category_id), ''),
+	COALESCE(kitchen_id, 0), COALESCE(to_char(available_from, 'HH24:MI'), ''), COALESCE(to_char(available_until, 'HH24:MI'), ''), stock,
+	allergens IS NOT NULL, COALESCE(allergens, '{}'), kcal::float8, protein::float8, fat::float8, carbs::float8, tax_category`
+
+func scanProduct(row pgx.Row) (Product, error) {
+	var p Product
+	var hasAllergens bool
+	var allergens []string
+	var kcal, protein, fat, carbs *float64
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.ImageURL, &p.IsAvailable, &p.SKU, &p.Category,
+		&p.KitchenID, &p.AvailableFrom, &p.AvailableUntil, &p.Stock,
+		&hasAllergens, &allergens, &kcal, &protein, &fat, &carbs, &p.TaxCategory)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Product{}, ErrNotFound
+	}
+	p.SoldOut = p.Stock != nil && *p.Stock == 0
+	if hasAllergens {
+		p.Allergens = append([]string{}, allergens...)
+	}
+	// КБЖУ пишутся только вместе, поэтому достаточно проверить калории
+	if kcal != nil {
+		p.Nutrition = &diet.Nutrition{Kcal: *kcal, Protein: deref(protein), Fat: deref(fat), Carbs: deref(carbs)}
+	}
+	return p, err
+}
+
+func deref(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// nutritionArgs раскладывает КБЖУ по колонкам; nil — все четыре NULL
+func nutritionArgs(n *diet.Nutrition) (kcal, protein, fat, carbs *float64) {
+	if n == nil {
+		return nil, nil, nil, nil
+	}
+	return &n.Kcal, &n.Protein, &n.Fat, &n.Carbs
+}
+
+// mapWriteError превращает нарушение внешнего ключа на kitchen_id в понятную ошибку
+func mapWriteError(err error) error {
+	if isFKViolation(err) {
+		return ErrUnknownKitchen
+	}
+	return err
+}
+
+// isFKViolation — Postgres отверг запись из-за внешнего ключа (код 23503).
+// У products и kitchen_exceptions внешний ключ на кухню — единственный, который может нарушить клиент.
+func isFKViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
 func (r *pgRepo) Create(ctx context.Context, p Product) (int64, error) {
+	// Товар и его опции сохраняем одной транзакцией
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	categoryID, err := ensureCategory(ctx, tx, p.Category)
+	if err != nil {
+		return 0, err
+	}
+
 	var id int64
-	query := "INSERT INTO products (name, description, price, image_url) VALUES ($1, $2, $3, $4) RETURNING id"
-	err := r.db.QueryRow(ctx, query, p.Name, p.Description, p.Price, p.ImageURL).Scan(&id)
-	return id, err
+	query := `
+		INSERT INTO products (name, description, price, image_url, is_available, sku, category_id,
+			kitchen_id, available_from, available_until, stock, allergens, kcal, protein, fat, carbs, tax_category)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, '')::time, NULLIF($10, '')::time, $11,
+			$12, $13, $14, $15, $16, $17) RETURNING id`
+	kcal, protein, fat, carbs := nutritionArgs(p.Nutrition)
+	err = tx.QueryRow(ctx, query, p.Name, p.Description, p.Price, p.ImageURL, p.IsAvailable, nullIfEmpty(p.SKU), categoryID,
+		p.KitchenID, p.AvailableFrom, p.AvailableUntil, p.Stock, p.Allergens, kcal, protein, fat, carbs, p.TaxCategory).Scan(&id)
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	if err := replaceOptionGroups(ctx, tx, id, p.OptionGroups); err != nil {
+		return 0, err
+	}
+	if err := replaceTranslations(ctx, tx, id, p.Translations); err != nil {
+		return 0, err
+	}
+	created, err := loadProduct(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := enqueueProduct(ctx, tx, events.ProductCreated, created); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
 }
 
 func (r *pgRepo) List(ctx context.Context) ([]Product, error) {
-	query := "SELECT id, name, description, price, image_url FROM products"
+	// Удаленные товары в выдачу не попадают
+	query := "SELECT " + productColumns + " FROM products WHERE deleted_at IS NULL ORDER BY id"
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
@@ -44,8 +213,8 @@ func (r *pgRepo) List(ctx context.Context) ([]Product, error) {
 
 	var products []Product
 	for rows.Next() {
-		var p Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.ImageURL); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -53,5 +222,208 @@ func (r *pgRepo) List(ctx context.Context) ([]Product, error) {
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	if err := attachOptionGroups(ctx, r.db, products); err != nil {
+		return nil, err
+	}
+	if err := attachTranslations(ctx, r.db, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
+
+func (r *pgRepo) GetByID(ctx context.Context, id int64) (Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE id = $1 AND deleted_at IS NULL"
+	p, err := scanProduct(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return Product{}, err
+	}
+	products := []Product{p}
+	if err := attachOptionGroups(ctx, r.db, products); err != nil {
+		return Product{}, err
+	}
+	if err := attachTranslations(ctx, r.db, products); err != nil {
+		return Product{}, err
+	}
+	return products[0], nil
+}
+
+func (r *pgRepo) Update(ctx context.Context, p Product) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockAvailability(ctx, tx, p.ID)
+	if err != nil {
+		return err
+	}
+	categoryID, err := ensureCategory(ctx, tx, p.Category)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE products
+		SET name = $2, description = $3, price = $4, image_url = $5, is_available = $6,
+			sku = $7, category_id = $8, kitchen_id = NULLIF($9, 0),
+			available_from = NULLIF($10, '')::time, available_until = NULLIF($11, '')::time, stock = $12,
+			allergens = $13, kcal = $14, protein = $15, fat = $16, carbs = $17, tax_category = $18, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`
+	kcal, protein, fat, carbs := nutritionArgs(p.Nutrition)
+	result, err := tx.Exec(ctx, query, p.ID, p.Name, p.Description, p.Price, p.ImageURL, p.IsAvailable, nullIfEmpty(p.SKU), categoryID,
+		p.KitchenID, p.AvailableFrom, p.AvailableUntil, p.Stock, p.Allergens, kcal, protein, fat, carbs, p.TaxCategory)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	// PUT — полная замена, поэтому опции тоже заменяются.
+	// Переводы не трогаем: витрина отдает товар без них, и PUT "как прочитал" стер бы их.
+	if err := replaceOptionGroups(ctx, tx, p.ID, p.OptionGroups); err != nil {
+		return err
+	}
+	updated, err := loadProduct(ctx, tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if err := enqueueProduct(ctx, tx, events.ProductUpdated, updated); err != nil {
+		return err
+	}
+	if err := enqueueAvailability(ctx, tx, p.ID, before, stateOf(updated)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) Patch(ctx context.Context, id int64, patch ProductPatch) (Product, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return Product{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockAvailability(ctx, tx, id)
+	if err != nil {
+		return Product{}, err
+	}
+	// Категорию меняем, только если её передали; пустая строка убирает категорию
+	var categoryID *int64
+	if patch.Category != nil {
+		if categoryID, err = ensureCategory(ctx, tx, *patch.Category); err != nil {
+			return Product{}, err
+		}
+	}
+
+	// COALESCE оставляет старое значение, если поле не передали (NULL)
+	query := `
+		UPDATE products
+		SET name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			price = COALESCE($4, price),
+			image_url = COALESCE($5, image_url),
+			is_available = COALESCE($6, is_available),
+			sku = COALESCE(NULLIF($7, ''), sku),
+			category_id = CASE WHEN $8 THEN $9 ELSE category_id END,
+			kitchen_id = CASE WHEN $10::int IS NULL THEN kitchen_id ELSE NULLIF($10::int, 0) END,
+			available_from = CASE WHEN $11::text IS NULL THEN available_from ELSE NULLIF($11::text, '')::time END,
+			available_until = CASE WHEN $12::text IS NULL THEN available_until ELSE NULLIF($12::text, '')::time END,
+			stock = CASE WHEN $13::int IS NULL THEN stock ELSE NULLIF($13::int, -1) END,
+			allergens = COALESCE($14::text[], allergens),
+			kcal = CASE WHEN $15 THEN $16 ELSE kcal END,
+			protein = CASE WHEN $15 THEN $17 ELSE protein END,
+			fat = CASE WHEN $15 THEN $18 ELSE fat END,
+			carbs = CASE WHEN $15 THEN $19 ELSE carbs END,
+			tax_category = COALESCE($20, tax_category),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + productColumns
+	kcal, protein, fat, carbs := nutritionArgs(patch.Nutrition)
+	p, err := scanProduct(tx.QueryRow(ctx, query, id, patch.Name, patch.Description, patch.Price, patch.ImageURL, patch.IsAvailable,
+		patch.SKU, patch.Category != nil, categoryID, patch.KitchenID, patch.AvailableFrom, patch.AvailableUntil, patch.Stock,
+		patch.Allergens, patch.Nutrition != nil, kcal, protein, fat, carbs, patch.TaxCategory))
+	if err != nil {
+		return Product{}, mapWriteError(err)
+	}
+	if patch.OptionGroups != nil {
+		if err := replaceOptionGroups(ctx, tx, id, *patch.OptionGroups); err != nil {
+			return Product{}, err
+		}
+	}
+
+	products := []Product{p}
+	if err := attachOptionGroups(ctx, tx, products); err != nil {
+		return Product{}, err
+	}
+	if err := attachTranslations(ctx, tx, products); err != nil {
+		return Product{}, err
+	}
+	if err := enqueueProduct(ctx, tx, events.ProductUpdated, products[0]); err != nil {
+		return Product{}, err
+	}
+	if err := enqueueAvailability(ctx, tx, id, before, stateOf(products[0])); err != nil {
+		return Product{}, err
+	}
+	return products[0], tx.Commit(ctx)
+}
+
+func (r *pgRepo) Delete(ctx context.Context, id int64) error {
+	// Мягкое удаление: строка остается, чтобы не ломать старые заказы (order_items ссылается на products)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := "UPDATE products SET deleted_at = NOW(), is_available = FALSE, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL"
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := events.Enqueue(ctx, tx, events.ProductDeleted, events.ProductDeletedData{ProductID: id}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) SetAvailability(ctx context.Context, ids []int64, available bool) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Событие нужно только по товарам, которые реально стали (не)доступны для заказа:
+	// распроданный товар доступнее от снятия со стоп-листа не становится
+	query := `
+		SELECT id FROM products
+		WHERE id = ANY($1) AND deleted_at IS NULL AND is_available <> $2 AND COALESCE(stock, 1) > 0
+		ORDER BY id FOR UPDATE`
+	rows, err := tx.Query(ctx, query, ids, available)
+	if err != nil {
+		return 0, err
+	}
+	changed, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, err
+	}
+
+	query = "UPDATE products SET is_available = $2, updated_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL"
+	result, err := tx.Exec(ctx, query, ids, available)
+	if err != nil {
+		return 0, err
+	}
+	if len(changed) > 0 {
+		err = events.Enqueue(ctx, tx, events.AvailabilityChanged, events.AvailabilityChangedData{
+			ProductIDs: changed, IsAvailable: available, Reason: events.ReasonStopList,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return result.RowsAffected(), tx.Commit(ctx)
+}
@@ -0,0 +1,157 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/locale"
+	"github.com/jackc/pgx/v5"
+)
+
+// Translation — название и описание товара на другом языке
+type Translation struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"` // пустое — берется описание на основном языке
+}
+
+type TranslationRepository interface {
+	// SetTranslation создает или заменяет перевод товара на язык locale
+	SetTranslation(ctx context.Context, productID int64, locale string, t Translation) error
+	DeleteTranslation(ctx context.Context, productID int64, locale string) error
+}
+
+// Localize подставляет в товар название и описание на первом подходящем языке из prefs
+// ("en-gb" подходит и под перевод "en"). Если подходящего перевода нет, остается основной язык.
+// Переводы из ответа убираются: клиенту витрины нужен один язык.
+func (p Product) Localize(prefs []string) Product {
+	translations := p.Translations
+	p.Translations = nil
+	for _, tag := range prefs {
+		for _, candidate := range []string{tag, locale.Base(tag)} {
+			if candidate == locale.Default {
+				return p
+			}
+			t, ok := translations[candidate]
+			if !ok {
+				continue
+			}
+			p.Name = t.Name
+			if t.Description != "" {
+				p.Description = t.Description
+			}
+			return p
+		}
+	}
+	return p
+}
+
+// LocalizeAll — Localize для списка товаров
+func LocalizeAll(products []Product, prefs []string) []Product {
+	result := make([]Product, len(products))
+	for i, p := range products {
+		result[i] = p.Localize(prefs)
+	}
+	return result
+}
+
+func (r *pgRepo) SetTranslation(ctx context.Context, productID int64, locale string, t Translation) error {
+	// Переводить удаленный товар незачем, поэтому проверяем deleted_at, а не полагаемся на внешний ключ
+	query := `
+		INSERT INTO product_translations (product_id, locale, name, description)
+		SELECT id, $2, $3, $4 FROM products WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (product_id, locale) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description`
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, query, productID, locale, t.Name, t.Description)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := enqueueTranslated(ctx, tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) DeleteTranslation(ctx context.Context, productID int64, locale string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, "DELETE FROM product_translations WHERE product_id = $1 AND locale = $2", productID, locale)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := enqueueTranslated(ctx, tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// enqueueTranslated — смена перевода для поиска такое же изменение товара, как и правка названия
+func enqueueTranslated(ctx context.Context, tx pgx.Tx, productID int64) error {
+	p, err := loadProduct(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	return enqueueProduct(ctx, tx, events.ProductUpdated, p)
+}
+
+// replaceTranslations полностью заменяет переводы товара внутри транзакции
+func replaceTranslations(ctx context.Context, tx pgx.Tx, productID int64, translations map[string]Translation) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM product_translations WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	for tag, t := range translations {
+		_, err := tx.Exec(ctx, "INSERT INTO product_translations (product_id, locale, name, description) VALUES ($1, $2, $3, $4)",
+			productID, tag, t.Name, t.Description)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTranslations дописывает переводы к уже загруженным товарам
+func attachTranslations(ctx context.Context, q querier, products []Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(products))
+	index := make(map[int64]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+		index[p.ID] = i
+	}
+
+	rows, err := q.Query(ctx, "SELECT product_id, locale, name, description FROM product_translations WHERE product_id = ANY($1)", ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var productID int64
+		var tag string
+		var t Translation
+		if err := rows.Scan(&productID, &tag, &t.Name, &t.Description); err != nil {
+			return err
+		}
+		p := &products[index[productID]]
+		if p.Translations == nil {
+			p.Translations = make(map[string]Translation)
+		}
+		p.Translations[tag] = t
+	}
+	return rows.Err()
+}
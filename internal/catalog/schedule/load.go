@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier — общее у pgxpool.Pool и pgx.Tx
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Load читает расписания кухонь из базы. Прошедшие исключения не загружаются:
+// для "открыто ли сейчас" нужны только сегодня и вчера (ночные интервалы).
+func Load(ctx context.Context, q Querier, kitchenIDs []int64) (map[int64]Schedule, error) {
+	result := make(map[int64]Schedule, len(kitchenIDs))
+
+	rows, err := q.Query(ctx, "SELECT id, timezone FROM kitchens WHERE id = ANY($1)", kitchenIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var s Schedule
+		if err := rows.Scan(&id, &s.Timezone); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result[id] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT kitchen_id, weekday, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+		FROM kitchen_hours WHERE kitchen_id = ANY($1) ORDER BY kitchen_id, weekday, opens_at`, kitchenIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var h Hours
+		if err := rows.Scan(&id, &h.Weekday, &h.Opens, &h.Closes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s := result[id]
+		s.Hours = append(s.Hours, h)
+		result[id] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT kitchen_id, to_char(date, 'YYYY-MM-DD'), is_closed,
+			COALESCE(to_char(opens_at, 'HH24:MI'), ''), COALESCE(to_char(closes_at, 'HH24:MI'), ''), COALESCE(note, '')
+		FROM kitchen_exceptions
+		WHERE kitchen_id = ANY($1) AND date >= CURRENT_DATE - 1
+		ORDER BY kitchen_id, date`, kitchenIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var e Exception
+		if err := rows.Scan(&id, &e.Date, &e.Closed, &e.Opens, &e.Closes, &e.Note); err != nil {
+			return nil, err
+		}
+		s := result[id]
+		s.Exceptions = append(s.Exceptions, e)
+		result[id] = s
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,221 @@
+// Package schedule отвечает на вопрос "работает ли кухня сейчас":
+// недельное расписание в часовом поясе кухни, праздничные исключения
+// и временные окна отдельных позиций меню (завтраки до 11:00).
+// Пакет общий для каталога (фильтр "открыто сейчас") и заказов (запрет заказа в закрытую кухню).
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // в alpine-образе нет базы часовых поясов, вшиваем её в бинарник
+)
+
+var ErrInvalidSchedule = errors.New("некорректное расписание")
+
+const dateLayout = "2006-01-02"
+
+// Hours — интервал работы в конкретный день недели.
+// Если Closes раньше Opens (например, 18:00–02:00), интервал переходит через полночь.
+type Hours struct {
+	Weekday int    `json:"weekday"` // 0 — воскресенье, 1 — понедельник ... 6 — суббота (как time.Weekday)
+	Opens   string `json:"opens"`   // "09:00"
+	Closes  string `json:"closes"`  // "23:00", "24:00" — до конца суток
+}
+
+// Exception — особый день: праздник (Closed) или сокращенный/продленный график
+type Exception struct {
+	Date   string `json:"date"` // "2026-12-31" по местному времени кухни
+	Closed bool   `json:"closed"`
+	Opens  string `json:"opens,omitempty"`
+	Closes string `json:"closes,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Schedule — полное расписание кухни
+type Schedule struct {
+	Timezone   string      `json:"timezone"` // IANA, например "Europe/Moscow"
+	Hours      []Hours     `json:"hours"`
+	Exceptions []Exception `json:"exceptions"`
+}
+
+// window — интервал в минутах от полуночи
+type window struct {
+	opens, closes int
+}
+
+// parseClock разбирает "HH:MM" в минуты от полуночи; "24:00" допустимо как конец суток
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("%w: время %q, ожидается ЧЧ:ММ", ErrInvalidSchedule, s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%w: время %q вне суток", ErrInvalidSchedule, s)
+	}
+	return h*60 + m, nil
+}
+
+func parseWindow(opens, closes string) (window, error) {
+	o, err := parseClock(opens)
+	if err != nil {
+		return window{}, err
+	}
+	c, err := parseClock(closes)
+	if err != nil {
+		return window{}, err
+	}
+	if o == c {
+		return window{}, fmt.Errorf("%w: интервал %s–%s пустой", ErrInvalidSchedule, opens, closes)
+	}
+	return window{opens: o, closes: c}, nil
+}
+
+func (w window) overnight() bool {
+	return w.closes < w.opens
+}
+
+// containsToday — попадает ли минута суток в часть интервала, относящуюся к его собственному дню
+func (w window) containsToday(minute int) bool {
+	if w.overnight() {
+		return minute >= w.opens
+	}
+	return minute >= w.opens && minute < w.closes
+}
+
+// containsSpillover — попадает ли минута в "хвост" вчерашнего ночного интервала
+func (w window) containsSpillover(minute int) bool {
+	return w.overnight() && minute < w.closes
+}
+
+// Validate проверяет часовой пояс, формат времени и дат
+func (s Schedule) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return fmt.Errorf("%w: неизвестный часовой пояс %q", ErrInvalidSchedule, s.Timezone)
+	}
+	for _, h := range s.Hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return fmt.Errorf("%w: день недели %d, ожидается 0–6", ErrInvalidSchedule, h.Weekday)
+		}
+		if _, err := parseWindow(h.Opens, h.Closes); err != nil {
+			return err
+		}
+	}
+	for _, e := range s.Exceptions {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate проверяет дату исключения и, если день не выходной, его интервал
+func (e Exception) Validate() error {
+	if err := ValidateDate(e.Date); err != nil {
+		return err
+	}
+	if e.Closed {
+		return nil
+	}
+	_, err := parseWindow(e.Opens, e.Closes)
+	return err
+}
+
+// ValidateDate проверяет формат даты ГГГГ-ММ-ДД
+func ValidateDate(date string) error {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("%w: дата %q, ожидается ГГГГ-ММ-ДД", ErrInvalidSchedule, date)
+	}
+	return nil
+}
+
+// windowsOn возвращает интервалы работы на конкретную местную дату с учетом исключений
+func (s Schedule) windowsOn(day time.Time) []window {
+	date := day.Format(dateLayout)
+	for _, e := range s.Exceptions {
+		if e.Date != date {
+			continue
+		}
+		if e.Closed {
+			return nil
+		}
+		if w, err := parseWindow(e.Opens, e.Closes); err == nil {
+			return []window{w}
+		}
+		return nil
+	}
+
+	var windows []window
+	for _, h := range s.Hours {
+		if time.Weekday(h.Weekday) != day.Weekday() {
+			continue
+		}
+		if w, err := parseWindow(h.Opens, h.Closes); err == nil {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// IsOpen — работает ли кухня в момент t. Учитывает ночные интервалы,
+// начавшиеся накануне (пятница 18:00–02:00 открыта в субботу в 01:00).
+func (s Schedule) IsOpen(t time.Time) (bool, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, fmt.Errorf("%w: часовой пояс %q", ErrInvalidSchedule, s.Timezone)
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, w := range s.windowsOn(local) {
+		if w.containsToday(minute) {
+			return true, nil
+		}
+	}
+	for _, w := range s.windowsOn(local.AddDate(0, 0, -1)) {
+		if w.containsSpillover(minute) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InWindow — доступна ли позиция меню с окном from–until в момент t по времени кухни.
+// Пустое окно означает "весь день работы кухни".
+func InWindow(from, until, timezone string, t time.Time) (bool, error) {
+	if from == "" && until == "" {
+		return true, nil
+	}
+	if from == "" {
+		from = "00:00"
+	}
+	if until == "" {
+		until = "24:00"
+	}
+	w, err := parseWindow(from, until)
+	if err != nil {
+		return false, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return false, fmt.Errorf("%w: часовой пояс %q", ErrInvalidSchedule, timezone)
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	return w.containsToday(minute) || w.containsSpillover(minute), nil
+}
+
+// ValidateWindow проверяет окно доступности позиции ("" — без ограничения)
+func ValidateWindow(from, until string) error {
+	if from == "" && until == "" {
+		return nil
+	}
+	if from == "" {
+		from = "00:00"
+	}
+	if until == "" {
+		until = "24:00"
+	}
+	_, err := parseWindow(from, until)
+	return err
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// moscow — местное время кухни в тестах; 2026-10-16 — пятница
+func moscow(t *testing.T, value string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
+
+func TestIsOpen(t *testing.T) {
+	s := Schedule{
+		Timezone: "Europe/Moscow",
+		Hours: []Hours{
+			{Weekday: 1, Opens: "09:00", Closes: "23:00"},
+			{Weekday: 5, Opens: "18:00", Closes: "02:00"}, // пятница через полночь
+			{Weekday: 0, Opens: "10:00", Closes: "14:00"}, // в воскресенье два интервала
+			{Weekday: 0, Opens: "16:00", Closes: "24:00"},
+		},
+		Exceptions: []Exception{
+			{Date: "2026-10-19", Closed: true, Note: "санитарный день"},
+			{Date: "2026-10-26", Opens: "12:00", Closes: "15:00"},
+		},
+	}
+	tests := []struct {
+		at   string
+		open bool
+	}{
+		{"2026-10-16 17:59", false},
+		{"2026-10-16 18:00", true},
+		{"2026-10-16 23:59", true},
+		{"2026-10-17 01:30", true}, // хвост пятничного интервала
+		{"2026-10-17 02:00", false},
+		{"2026-10-18 13:59", true},
+		{"2026-10-18 15:00", false},
+		{"2026-10-18 23:59", true},
+		{"2026-10-19 12:00", false}, // понедельник, но выходной по исключению
+		{"2026-10-26 10:00", false}, // сокращенный день
+		{"2026-10-26 12:30", true},
+		{"2026-10-27 12:30", false}, // во вторник кухня не работает
+	}
+	for _, tt := range tests {
+		t.Run(tt.at, func(t *testing.T) {
+			open, err := s.IsOpen(moscow(t, tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if open != tt.open {
+				t.Fatalf("IsOpen(%s) = %v, ожидали %v", tt.at, open, tt.open)
+			}
+		})
+	}
+}
+
+func TestIsOpenUsesKitchenTimezone(t *testing.T) {
+	s := Schedule{Timezone: "Asia/Yekaterinburg", Hours: []Hours{{Weekday: 5, Opens: "09:00", Closes: "10:00"}}}
+	// 07:30 в Москве — 09:30 в Екатеринбурге
+	open, err := s.IsOpen(moscow(t, "2026-10-16 07:30"))
+	if err != nil || !open {
+		t.Fatalf("IsOpen() = %v, %v", open, err)
+	}
+	if _, err := (Schedule{Timezone: "Mars/Olympus"}).IsOpen(time.Now()); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("неизвестный пояс: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Schedule
+		ok   bool
+	}{
+		{"обычный график", Schedule{Timezone: "Europe/Moscow", Hours: []Hours{{Weekday: 1, Opens: "09:00", Closes: "24:00"}}}, true},
+		{"без пояса", Schedule{}, false},
+		{"день недели 7", Schedule{Timezone: "UTC", Hours: []Hours{{Weekday: 7, Opens: "09:00", Closes: "10:00"}}}, false},
+		{"пустой интервал", Schedule{Timezone: "UTC", Hours: []Hours{{Weekday: 1, Opens: "09:00", Closes: "09:00"}}}, false},
+		{"25 часов", Schedule{Timezone: "UTC", Hours: []Hours{{Weekday: 1, Opens: "09:00", Closes: "25:00"}}}, false},
+		{"24:30", Schedule{Timezone: "UTC", Hours: []Hours{{Weekday: 1, Opens: "09:00", Closes: "24:30"}}}, false},
+		{"без ведущего нуля", Schedule{Timezone: "UTC", Hours: []Hours{{Weekday: 1, Opens: "9:00", Closes: "10:00"}}}, false},
+		{"праздник", Schedule{Timezone: "UTC", Exceptions: []Exception{{Date: "2026-12-31", Closed: true}}}, true},
+		{"кривая дата", Schedule{Timezone: "UTC", Exceptions: []Exception{{Date: "31.12.2026", Closed: true}}}, false},
+		{"особый день без часов", Schedule{Timezone: "UTC", Exceptions: []Exception{{Date: "2026-12-31"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("Validate() = %v, ожидали ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	tests := []struct {
+		from, until string
+		at          string
+		ok          bool
+	}{
+		{"", "", "2026-10-16 03:00", true},
+		{"08:00", "11:00", "2026-10-16 08:00", true},
+		{"08:00", "11:00", "2026-10-16 11:00", false},
+		{"", "11:00", "2026-10-16 00:10", true},
+		{"18:00", "", "2026-10-16 23:59", true},
+		{"22:00", "03:00", "2026-10-16 02:00", true}, // ночное окно
+		{"22:00", "03:00", "2026-10-16 12:00", false},
+	}
+	for _, tt := range tests {
+		ok, err := InWindow(tt.from, tt.until, "Europe/Moscow", moscow(t, tt.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok {
+			t.Errorf("InWindow(%q, %q, %s) = %v, ожидали %v", tt.from, tt.until, tt.at, ok, tt.ok)
+		}
+	}
+	if err := ValidateWindow("11:00", "11:00"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("ValidateWindow с пустым окном = %v", err)
+	}
+	if err := ValidateWindow("", ""); err != nil {
+		t.Fatalf("ValidateWindow без окна = %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/cache"
+)
+
+// GenKey — счетчик поколений кэша каталога (см. cache.Generation).
+// Экспортирован для сервиса заказов: он меняет остатки товаров в обход каталога.
+const GenKey = "catalog:gen"
+
+// cachedService — обертка над Service: чтение идет через Redis,
+// любое изменение меню сбрасывает кэш каталога целиком.
+type cachedService struct {
+	Service
+	cache *cache.Cache
+}
+
+// NewCached оборачивает сервис каталога read-through кэшем
+func NewCached(next Service, c *cache.Cache) Service {
+	return &cachedService{Service: next, cache: c}
+}
+
+// GetAllProducts кэширует полный список, а фильтры применяет поверх: у фильтров
+// по составу слишком много сочетаний, а "открыто сейчас" зависит от текущего времени
+func (s *cachedService) GetAllProducts(ctx context.Context, f ListFilter) ([]pg.Product, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	products, err := readThrough(ctx, s.cache, "products", func(ctx context.Context) ([]pg.Product, error) {
+		return s.Service.GetAllProducts(ctx, ListFilter{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	products = filterDiet(products, f)
+	if !f.OpenNow {
+		return products, nil
+	}
+	kitchens, err := readThrough(ctx, s.cache, "kitchens", s.Service.ListKitchens)
+	if err != nil {
+		return nil, err
+	}
+	return filterOpenNow(products, kitchens, time.Now()), nil
+}
+
+func (s *cachedService) GetProduct(ctx context.Context, id int64) (pg.Product, error) {
+	return readThrough(ctx, s.cache, fmt.Sprintf("product:%d", id), func(ctx context.Context) (pg.Product, error) {
+		return s.Service.GetProduct(ctx, id)
+	})
+}
+
+func (s *cachedService) AddProduct(ctx context.Context, p pg.Product) (int64, error) {
+	id, err := s.Service.AddProduct(ctx, p)
+	s.invalidate(ctx, err)
+	return id, err
+}
+
+func (s *cachedService) UpdateProduct(ctx context.Context, p pg.Product) error {
+	err := s.Service.UpdateProduct(ctx, p)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) PatchProduct(ctx context.Context, id int64, patch pg.ProductPatch) (pg.Product, error) {
+	p, err := s.Service.PatchProduct(ctx, id, patch)
+	s.invalidate(ctx, err)
+	return p, err
+}
+
+func (s *cachedService) DeleteProduct(ctx context.Context, id int64) error {
+	err := s.Service.DeleteProduct(ctx, id)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) SetTranslation(ctx context.Context, productID int64, locale string, t pg.Translation) error {
+	err := s.Service.SetTranslation(ctx, productID, locale, t)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) DeleteTranslation(ctx context.Context, productID int64, locale string) error {
+	err := s.Service.DeleteTranslation(ctx, productID, locale)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) SetStopList(ctx context.Context, ids []int64, available bool) (int64, error) {
+	n, err := s.Service.SetStopList(ctx, ids, available)
+	s.invalidate(ctx, err)
+	return n, err
+}
+
+func (s *cachedService) CreateKitchen(ctx context.Context, k pg.Kitchen) (int64, error) {
+	id, err := s.Service.CreateKitchen(ctx, k)
+	s.invalidate(ctx, err)
+	return id, err
+}
+
+func (s *cachedService) UpdateKitchen(ctx context.Context, k pg.Kitchen) error {
+	err := s.Service.UpdateKitchen(ctx, k)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) SetKitchenException(ctx context.Context, kitchenID int64, e schedule.Exception) error {
+	err := s.Service.SetKitchenException(ctx, kitchenID, e)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) DeleteKitchenException(ctx context.Context, kitchenID int64, date string) error {
+	err := s.Service.DeleteKitchenException(ctx, kitchenID, date)
+	s.invalidate(ctx, err)
+	return err
+}
+
+func (s *cachedService) ImportMenu(ctx context.Context, categories []pg.Category, items []ImportItem, dryRun bool) (ImportReport, error) {
+	report, err := s.Service.ImportMenu(ctx, categories, items, dryRun)
+	if !dryRun {
+		s.invalidate(ctx, err)
+	}
+	return report, err
+}
+
+// readThrough достает значение из кэша, а при промахе — через load, сохраняя JSON в Redis
+func readThrough[T any](ctx context.Context, c *cache.Cache, name string, load func(ctx context.Context) (T, error)) (T, error) {
+	gen, err := c.Generation(ctx, GenKey)
+	if err != nil {
+		// Redis лежит — отдаем данные напрямую из базы
+		log.Printf("catalog cache: %v", err)
+		return load(ctx)
+	}
+
+	var result T
+	key := fmt.Sprintf("catalog:%d:%s", gen, name)
+	data, err := c.GetOrLoad(ctx, key, func(ctx context.Context) ([]byte, error) {
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// invalidate сбрасывает кэш после успешной записи (при ошибке транзакция откатилась)
+func (s *cachedService) invalidate(ctx context.Context, opErr error) {
+	if opErr != nil {
+		return
+	}
+	if err := s.cache.Invalidate(ctx, GenKey); err != nil {
+		log.Printf("catalog cache: не удалось сбросить кэш: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+)
+
+// ListFilter — фильтры витрины
+type ListFilter struct {
+	OpenNow          bool     // только то, что можно заказать прямо сейчас
+	ExcludeAllergens []string // без этих аллергенов; товары с неуказанным составом тоже отсекаются
+	MaxKcal          float64  // 0 — без ограничения; товары без КБЖУ отсекаются
+}
+
+// Validate проверяет аллергены по справочнику и приводит их к нижнему регистру
+func (f *ListFilter) Validate() error {
+	if f.MaxKcal < 0 || math.IsNaN(f.MaxKcal) || math.IsInf(f.MaxKcal, 0) {
+		return fmt.Errorf("%w: max_kcal должен быть неотрицательным числом", diet.ErrInvalidNutrition)
+	}
+	allergens, err := diet.NormalizeAllergens(f.ExcludeAllergens)
+	if err != nil {
+		return err
+	}
+	f.ExcludeAllergens = allergens
+	return nil
+}
+
+// filterDiet применяет фильтры по составу. Если данных нет, товар не показываем:
+// человеку с аллергией "не указано" не означает "можно".
+func filterDiet(products []pg.Product, f ListFilter) []pg.Product {
+	if len(f.ExcludeAllergens) == 0 && f.MaxKcal == 0 {
+		return products
+	}
+	result := make([]pg.Product, 0, len(products))
+	for _, p := range products {
+		if len(f.ExcludeAllergens) > 0 && (p.Allergens == nil || diet.Contains(p.Allergens, f.ExcludeAllergens)) {
+			continue
+		}
+		if f.MaxKcal > 0 && (p.Nutrition == nil || p.Nutrition.Kcal > f.MaxKcal) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
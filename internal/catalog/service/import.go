@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+)
+
+var (
+	ErrEmptySKU      = errors.New("у товара не указан sku")
+	ErrInvalidImport = errors.New("некорректный файл импорта")
+)
+
+// Menu — меню целиком: формат экспорта и JSON-импорта
+type Menu struct {
+	Categories []pg.Category `json:"categories"`
+	Products   []pg.Product  `json:"products"`
+}
+
+// ImportItem — одна строка файла импорта. Err заполняется, если строку не удалось даже разобрать.
+type ImportItem struct {
+	Row     int
+	Product pg.Product
+	Err     error
+}
+
+// Действия над строкой в отчете импорта
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionError  = "error"
+)
+
+type ImportRow struct {
+	Row       int      `json:"row"`
+	SKU       string   `json:"sku"`
+	Action    string   `json:"action"`
+	ProductID int64    `json:"product_id,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ImportReport — построчный отчет: что создано, что обновлено, где ошибки
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// ImportMenu проверяет каждую строку и сохраняет валидные одной транзакцией (upsert по sku).
+// Строки с ошибками (в том числе со ссылкой на несуществующую кухню) пропускаются и попадают в отчет.
+// В режиме dryRun база не меняется, но отчет показывает, что было бы создано и обновлено.
+func (s *catalogService) ImportMenu(ctx context.Context, categories []pg.Category, items []ImportItem, dryRun bool) (ImportReport, error) {
+	for _, c := range categories {
+		if c.Name == "" {
+			return ImportReport{}, fmt.Errorf("%w: категория без названия", ErrInvalidImport)
+		}
+	}
+
+	kitchens, err := s.importKitchens(ctx, items)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report := ImportReport{DryRun: dryRun, Total: len(items)}
+
+	var valid []pg.Product
+	var validRows []int // индекс в report.Rows для каждого валидного товара
+	seen := make(map[string]int)
+	for _, item := range items {
+		row := ImportRow{Row: item.Row, SKU: item.Product.SKU}
+		if errs := validateImportItem(&item, seen, kitchens); len(errs) > 0 {
+			row.Action = ActionError
+			row.Errors = errs
+			report.Failed++
+		} else {
+			valid = append(valid, item.Product)
+			validRows = append(validRows, len(report.Rows))
+		}
+		seen[item.Product.SKU] = item.Row
+		report.Rows = append(report.Rows, row)
+	}
+	if len(valid) == 0 && len(categories) == 0 {
+		return report, nil
+	}
+
+	if dryRun {
+		skus := make([]string, len(valid))
+		for i, p := range valid {
+			skus[i] = p.SKU
+		}
+		existing, err := s.repo.FindIDsBySKU(ctx, skus)
+		if err != nil {
+			return report, err
+		}
+		for i, p := range valid {
+			row := &report.Rows[validRows[i]]
+			if id, ok := existing[p.SKU]; ok {
+				row.Action, row.ProductID = ActionUpdate, id
+				report.Updated++
+			} else {
+				row.Action = ActionCreate
+				report.Created++
+			}
+		}
+		return report, nil
+	}
+
+	results, err := s.repo.UpsertBySKU(ctx, categories, valid)
+	if err != nil {
+		return report, kitchenWriteError(err)
+	}
+	for i, res := range results {
+		row := &report.Rows[validRows[i]]
+		row.ProductID = res.ID
+		if res.Created {
+			row.Action = ActionCreate
+			report.Created++
+		} else {
+			row.Action = ActionUpdate
+			report.Updated++
+		}
+	}
+	return report, nil
+}
+
+// importKitchens одним запросом проверяет все кухни, на которые ссылается файл
+func (s *catalogService) importKitchens(ctx context.Context, items []ImportItem) (map[int64]bool, error) {
+	var ids []int64
+	for _, item := range items {
+		if item.Err == nil && item.Product.KitchenID != 0 {
+			ids = append(ids, item.Product.KitchenID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.repo.ExistingKitchens(ctx, ids)
+}
+
+func validateImportItem(item *ImportItem, seen map[string]int, kitchens map[int64]bool) []string {
+	if item.Err != nil {
+		return []string{item.Err.Error()}
+	}
+	var errs []string
+	if item.Product.SKU == "" {
+		errs = append(errs, ErrEmptySKU.Error())
+	} else if prev, ok := seen[item.Product.SKU]; ok {
+		errs = append(errs, fmt.Sprintf("sku %q уже встречался в строке %d", item.Product.SKU, prev))
+	}
+	if err := prepareProduct(&item.Product); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if id := item.Product.KitchenID; id != 0 && !kitchens[id] {
+		errs = append(errs, fmt.Sprintf("%v: %d", ErrInvalidKitchen, id))
+	}
+	return errs
+}
+
+// ExportMenu выгружает всё живое меню для бэкапа или переноса в другой ресторан
+func (s *catalogService) ExportMenu(ctx context.Context) (Menu, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return Menu{}, err
+	}
+	products, err := s.repo.List(ctx)
+	if err != nil {
+		return Menu{}, err
+	}
+	return Menu{Categories: categories, Products: products}, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+func TestValidateImportItem(t *testing.T) {
+	kitchens := map[int64]bool{1: true}
+	seen := map[string]int{"dup": 2}
+	item := func(sku string, kitchenID int64) *ImportItem {
+		return &ImportItem{Row: 5, Product: pg.Product{
+			Name: "Суп", SKU: sku, Price: money.FromMajor(100), KitchenID: kitchenID,
+		}}
+	}
+
+	if errs := validateImportItem(item("a", 0), seen, kitchens); len(errs) != 0 {
+		t.Fatalf("товар без кухни: %v", errs)
+	}
+	if errs := validateImportItem(item("a", 1), seen, kitchens); len(errs) != 0 {
+		t.Fatalf("существующая кухня: %v", errs)
+	}
+	if errs := validateImportItem(item("a", 7), seen, kitchens); len(errs) != 1 {
+		t.Fatalf("несуществующая кухня должна дать одну ошибку строки, получили %v", errs)
+	}
+	// Ошибки строки копятся, а не обрываются на первой
+	if errs := validateImportItem(item("dup", 7), seen, nil); len(errs) != 2 {
+		t.Fatalf("дубль sku и чужая кухня: %v", errs)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+)
+
+var ErrEmptyKitchenName = errors.New("название кухни не может быть пустым")
+
+// DefaultTimezone — в нем считается окно подачи товаров, не привязанных к кухне
+const DefaultTimezone = "Europe/Moscow"
+
+// checkKitchen проверяет, что товар ссылается на существующую кухню; 0 — товар без кухни
+func (s *catalogService) checkKitchen(ctx context.Context, id int64) error {
+	if id == 0 {
+		return nil
+	}
+	found, err := s.repo.ExistingKitchens(ctx, []int64{id})
+	if err != nil {
+		return err
+	}
+	if !found[id] {
+		return fmt.Errorf("%w: %d", ErrInvalidKitchen, id)
+	}
+	return nil
+}
+
+// kitchenWriteError превращает нарушение внешнего ключа при записи товара в ошибку валидации:
+// для клиента это неверный kitchen_id в теле запроса, а не отсутствующий ресурс
+func kitchenWriteError(err error) error {
+	if errors.Is(err, pg.ErrUnknownKitchen) {
+		return ErrInvalidKitchen
+	}
+	return err
+}
+
+func (s *catalogService) CreateKitchen(ctx context.Context, k pg.Kitchen) (int64, error) {
+	if err := validateKitchen(k); err != nil {
+		return 0, err
+	}
+	return s.repo.CreateKitchen(ctx, k)
+}
+
+func (s *catalogService) ListKitchens(ctx context.Context) ([]pg.Kitchen, error) {
+	kitchens, err := s.repo.ListKitchens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range kitchens {
+		kitchens[i].OpenNow, _ = kitchens[i].IsOpen(now)
+	}
+	return kitchens, nil
+}
+
+func (s *catalogService) GetKitchen(ctx context.Context, id int64) (pg.Kitchen, error) {
+	k, err := s.repo.GetKitchen(ctx, id)
+	if err != nil {
+		return pg.Kitchen{}, err
+	}
+	k.OpenNow, _ = k.IsOpen(time.Now())
+	return k, nil
+}
+
+func (s *catalogService) UpdateKitchen(ctx context.Context, k pg.Kitchen) error {
+	if err := validateKitchen(k); err != nil {
+		return err
+	}
+	return s.repo.UpdateKitchen(ctx, k)
+}
+
+func (s *catalogService) SetKitchenException(ctx context.Context, kitchenID int64, e schedule.Exception) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	return s.repo.SetKitchenException(ctx, kitchenID, e)
+}
+
+func (s *catalogService) DeleteKitchenException(ctx context.Context, kitchenID int64, date string) error {
+	if err := schedule.ValidateDate(date); err != nil {
+		return err
+	}
+	return s.repo.DeleteKitchenException(ctx, kitchenID, date)
+}
+
+func validateKitchen(k pg.Kitchen) error {
+	if k.Name == "" {
+		return ErrEmptyKitchenName
+	}
+	return k.Schedule.Validate()
+}
+
+// filterOpenNow оставляет товары, которые можно заказать в момент now:
+// товар не в стоп-листе и не распродан, кухня работает и время попадает в окно подачи позиции
+func filterOpenNow(products []pg.Product, kitchens []pg.Kitchen, now time.Time) []pg.Product {
+	byID := make(map[int64]pg.Kitchen, len(kitchens))
+	for _, k := range kitchens {
+		byID[k.ID] = k
+	}
+
+	result := make([]pg.Product, 0, len(products))
+	for _, p := range products {
+		if !p.IsAvailable || p.SoldOut {
+			continue
+		}
+		timezone := DefaultTimezone
+		if p.KitchenID != 0 {
+			k, ok := byID[p.KitchenID]
+			if !ok {
+				continue
+			}
+			open, err := k.IsOpen(now)
+			if err != nil {
+				log.Printf("catalog: расписание кухни %d: %v", k.ID, err)
+				continue
+			}
+			if !open {
+				continue
+			}
+			timezone = k.Timezone
+		}
+		if ok, _ := schedule.InWindow(p.AvailableFrom, p.AvailableUntil, timezone, now); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
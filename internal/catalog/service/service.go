@@ -3,13 +3,53 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/diet"
 	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+)
+
+// Ошибки валидации — хендлер отдает их клиенту как 400
+var (
+	ErrInvalidPrice   = errors.New("цена должна быть больше нуля")
+	ErrEmptyName      = errors.New("название товара не может быть пустым")
+	ErrNoProducts     = errors.New("не передан ни один товар")
+	ErrInvalidGroup   = errors.New("некорректная группа опций")
+	ErrInvalidStock   = errors.New("остаток не может быть отрицательным")
+	ErrInvalidKitchen = errors.New("kitchen_id: такой кухни нет")
+	// В базе цены лежат без кода валюты и читаются как рубли, поэтому другую валюту не принимаем
+	ErrInvalidCurrency = errors.New("цены принимаются только в " + money.DefaultCurrency)
 )
 
 type Service interface {
 	AddProduct(ctx context.Context, p pg.Product) (int64, error)
-	GetAllProducts(ctx context.Context) ([]pg.Product, error)
+	GetAllProducts(ctx context.Context, f ListFilter) ([]pg.Product, error)
+	GetProduct(ctx context.Context, id int64) (pg.Product, error)
+	UpdateProduct(ctx context.Context, p pg.Product) error
+	PatchProduct(ctx context.Context, id int64, patch pg.ProductPatch) (pg.Product, error)
+	DeleteProduct(ctx context.Context, id int64) error
+	// SetStopList ставит товары в стоп-лист (available = false) или возвращает их в продажу
+	SetStopList(ctx context.Context, ids []int64, available bool) (int64, error)
+
+	// Массовый импорт/экспорт меню (см. import.go)
+	ImportMenu(ctx context.Context, categories []pg.Category, items []ImportItem, dryRun bool) (ImportReport, error)
+	ExportMenu(ctx context.Context) (Menu, error)
+
+	// Кухни и их расписание (см. kitchens.go)
+	CreateKitchen(ctx context.Context, k pg.Kitchen) (int64, error)
+	ListKitchens(ctx context.Context) ([]pg.Kitchen, error)
+	GetKitchen(ctx context.Context, id int64) (pg.Kitchen, error)
+	UpdateKitchen(ctx context.Context, k pg.Kitchen) error
+	SetKitchenException(ctx context.Context, kitchenID int64, e schedule.Exception) error
+	DeleteKitchenException(ctx context.Context, kitchenID int64, date string) error
+
+	// Переводы названия и описания товара (см. translations.go)
+	SetTranslation(ctx context.Context, productID int64, locale string, t pg.Translation) error
+	DeleteTranslation(ctx context.Context, productID int64, locale string) error
 }
 
 type catalogService struct {
@@ -20,13 +60,190 @@ func New(r pg.Repository) Service {
 	return &catalogService{repo: r}
 }
 
+// prepareProduct проверяет товар перед записью и нормализует справочные поля:
+// коды языков переводов и аллергены
+func prepareProduct(p *pg.Product) error {
+	if p.Name == "" {
+		return ErrEmptyName
+	}
+	if !p.Price.IsPositive() {
+		return ErrInvalidPrice
+	}
+	if err := checkCurrency(p.Price); err != nil {
+		return err
+	}
+	if err := schedule.ValidateWindow(p.AvailableFrom, p.AvailableUntil); err != nil {
+		return err
+	}
+	if p.Stock != nil && *p.Stock < 0 {
+		return ErrInvalidStock
+	}
+	if p.Nutrition != nil {
+		if err := p.Nutrition.Validate(); err != nil {
+			return err
+		}
+	}
+	allergens, err := diet.NormalizeAllergens(p.Allergens)
+	if err != nil {
+		return err
+	}
+	p.Allergens = allergens
+	if p.TaxCategory, err = tax.Parse(string(p.TaxCategory)); err != nil {
+		return err
+	}
+	translations, err := normalizeTranslations(p.Translations)
+	if err != nil {
+		return err
+	}
+	p.Translations = translations
+	return validateOptionGroups(p.OptionGroups)
+}
+
+// checkCurrency пропускает только рубли; пустая валюта в запросе тоже означает рубли
+func checkCurrency(m money.Money) error {
+	if m.Currency != "" && m.Currency != money.DefaultCurrency {
+		return fmt.Errorf("%w: %s", ErrInvalidCurrency, m.Currency)
+	}
+	return nil
+}
+
+// validateOptionGroups проверяет, что правила выбора в группах выполнимы
+func validateOptionGroups(groups []pg.OptionGroup) error {
+	for _, g := range groups {
+		switch {
+		case g.Name == "":
+			return fmt.Errorf("%w: пустое название", ErrInvalidGroup)
+		case len(g.Options) == 0:
+			return fmt.Errorf("%w: в группе %q нет опций", ErrInvalidGroup, g.Name)
+		case g.MinSelect < 0 || g.MaxSelect < 1 || g.MinSelect > g.MaxSelect:
+			return fmt.Errorf("%w: в группе %q неверные min/max", ErrInvalidGroup, g.Name)
+		case g.MinSelect > len(g.Options):
+			return fmt.Errorf("%w: в группе %q min больше числа опций", ErrInvalidGroup, g.Name)
+		}
+		for _, o := range g.Options {
+			if o.Name == "" {
+				return fmt.Errorf("%w: в группе %q опция без названия", ErrInvalidGroup, g.Name)
+			}
+			if err := checkCurrency(o.PriceDelta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (s *catalogService) AddProduct(ctx context.Context, p pg.Product) (int64, error) {
-	if p.Price <= 0 {
-		return 0, errors.New("цена должна быть больше нуля")
+	if err := prepareProduct(&p); err != nil {
+		return 0, err
 	}
-	return s.repo.Create(ctx, p)
+	if err := s.checkKitchen(ctx, p.KitchenID); err != nil {
+		return 0, err
+	}
+	id, err := s.repo.Create(ctx, p)
+	return id, kitchenWriteError(err)
 }
 
-func (s *catalogService) GetAllProducts(ctx context.Context) ([]pg.Product, error) {
-	return s.repo.List(ctx)
+func (s *catalogService) GetAllProducts(ctx context.Context, f ListFilter) ([]pg.Product, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	products, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	products = filterDiet(products, f)
+	if !f.OpenNow {
+		return products, nil
+	}
+	kitchens, err := s.repo.ListKitchens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterOpenNow(products, kitchens, time.Now()), nil
+}
+
+func (s *catalogService) GetProduct(ctx context.Context, id int64) (pg.Product, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *catalogService) UpdateProduct(ctx context.Context, p pg.Product) error {
+	if err := prepareProduct(&p); err != nil {
+		return err
+	}
+	if err := s.checkKitchen(ctx, p.KitchenID); err != nil {
+		return err
+	}
+	return kitchenWriteError(s.repo.Update(ctx, p))
+}
+
+func (s *catalogService) PatchProduct(ctx context.Context, id int64, patch pg.ProductPatch) (pg.Product, error) {
+	if patch.Name != nil && *patch.Name == "" {
+		return pg.Product{}, ErrEmptyName
+	}
+	if patch.Price != nil {
+		if !patch.Price.IsPositive() {
+			return pg.Product{}, ErrInvalidPrice
+		}
+		if err := checkCurrency(*patch.Price); err != nil {
+			return pg.Product{}, err
+		}
+	}
+	if patch.Stock != nil && *patch.Stock < -1 {
+		return pg.Product{}, ErrInvalidStock
+	}
+	if patch.AvailableFrom != nil || patch.AvailableUntil != nil {
+		// Проверяем формат переданных концов окна
+		from, until := "", ""
+		if patch.AvailableFrom != nil {
+			from = *patch.AvailableFrom
+		}
+		if patch.AvailableUntil != nil {
+			until = *patch.AvailableUntil
+		}
+		if err := schedule.ValidateWindow(from, until); err != nil {
+			return pg.Product{}, err
+		}
+	}
+	if patch.Nutrition != nil {
+		if err := patch.Nutrition.Validate(); err != nil {
+			return pg.Product{}, err
+		}
+	}
+	if patch.Allergens != nil {
+		allergens, err := diet.NormalizeAllergens(*patch.Allergens)
+		if err != nil {
+			return pg.Product{}, err
+		}
+		patch.Allergens = &allergens
+	}
+	if patch.OptionGroups != nil {
+		if err := validateOptionGroups(*patch.OptionGroups); err != nil {
+			return pg.Product{}, err
+		}
+	}
+	if patch.TaxCategory != nil {
+		category, err := tax.Parse(string(*patch.TaxCategory))
+		if err != nil {
+			return pg.Product{}, err
+		}
+		patch.TaxCategory = &category
+	}
+	if patch.KitchenID != nil {
+		if err := s.checkKitchen(ctx, *patch.KitchenID); err != nil {
+			return pg.Product{}, err
+		}
+	}
+	p, err := s.repo.Patch(ctx, id, patch)
+	return p, kitchenWriteError(err)
+}
+
+func (s *catalogService) DeleteProduct(ctx context.Context, id int64) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *catalogService) SetStopList(ctx context.Context, ids []int64, available bool) (int64, error) {
+	if len(ids) == 0 {
+		return 0, ErrNoProducts
+	}
+	return s.repo.SetAvailability(ctx, ids, available)
 }
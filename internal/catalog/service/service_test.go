@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+func TestPrepareProductCurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		price money.Money
+		delta money.Money
+		err   error
+	}{
+		{"рубли", money.FromMajor(350), money.Zero(), nil},
+		{"валюта не указана", money.New(35000, ""), money.New(5000, ""), nil},
+		{"цена в долларах", money.New(500, "USD"), money.Zero(), ErrInvalidCurrency},
+		{"надбавка в евро", money.FromMajor(350), money.New(100, "EUR"), ErrInvalidCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := pg.Product{
+				Name:  "Борщ",
+				Price: tt.price,
+				OptionGroups: []pg.OptionGroup{{
+					Name: "Сметана", MaxSelect: 1,
+					Options: []pg.Option{{Name: "Двойная", PriceDelta: tt.delta}},
+				}},
+			}
+			if err := prepareProduct(&p); !errors.Is(err, tt.err) {
+				t.Fatalf("prepareProduct() = %v, ожидали %v", err, tt.err)
+			}
+		})
+	}
+}
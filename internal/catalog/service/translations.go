@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/repo/pg"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/locale"
+)
+
+func (s *catalogService) SetTranslation(ctx context.Context, productID int64, tag string, t pg.Translation) error {
+	tag, err := validateTranslation(tag, t)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetTranslation(ctx, productID, tag, t)
+}
+
+func (s *catalogService) DeleteTranslation(ctx context.Context, productID int64, tag string) error {
+	tag, err := locale.Normalize(tag)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteTranslation(ctx, productID, tag)
+}
+
+// validateTranslation проверяет перевод и возвращает нормализованный код языка
+func validateTranslation(tag string, t pg.Translation) (string, error) {
+	tag, err := locale.Normalize(tag)
+	if err != nil {
+		return "", err
+	}
+	if tag == locale.Default {
+		return "", fmt.Errorf("%w: %q — основной язык, он задается в самом товаре", locale.ErrInvalid, tag)
+	}
+	if t.Name == "" {
+		return "", fmt.Errorf("%w (перевод %q)", ErrEmptyName, tag)
+	}
+	return tag, nil
+}
+
+// normalizeTranslations проверяет переводы товара и приводит коды языков к нижнему регистру
+func normalizeTranslations(translations map[string]pg.Translation) (map[string]pg.Translation, error) {
+	if translations == nil {
+		return nil, nil
+	}
+	result := make(map[string]pg.Translation, len(translations))
+	for tag, t := range translations {
+		normalized, err := validateTranslation(tag, t)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := result[normalized]; ok {
+			return nil, fmt.Errorf("%w: перевод %q указан дважды", locale.ErrInvalid, normalized)
+		}
+		result[normalized] = t
+	}
+	return result, nil
+}
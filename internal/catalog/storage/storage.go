@@ -0,0 +1,108 @@
+// Package storage хранит файлы каталога (картинки товаров).
+// Сервис работает только с интерфейсом Storage, поэтому локальный диск
+// можно будет заменить на S3-совместимое хранилище без правок в хендлерах.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound    = errors.New("файл не найден")
+	ErrInvalidName = errors.New("недопустимое имя файла")
+)
+
+type Storage interface {
+	// Save записывает файл под именем name (вида "products/12/abc.jpg"), перезаписывая старый
+	Save(ctx context.Context, name string, r io.Reader) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// localStorage — реализация на локальной файловой системе
+type localStorage struct {
+	root string
+}
+
+// NewLocal создает хранилище в каталоге root (создает его при необходимости)
+func NewLocal(root string) (Storage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &localStorage{root: root}, nil
+}
+
+// path превращает имя в путь внутри root и не дает выйти за его пределы через "../"
+func (s *localStorage) path(name string) (string, error) {
+	clean := filepath.Clean("/" + name)
+	if clean == "/" || strings.Contains(name, "\x00") {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *localStorage) Save(ctx context.Context, name string, r io.Reader) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем: читатели никогда не увидят недописанную картинку
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Каталоги (например, "products/12") файлами не считаются
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return f, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -2,19 +2,32 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt" // Нужен для создания ошибок через fmt.Errorf
 
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Тариф курьера: фиксированная ставка за заказ плюс процент от суммы заказа
+var deliveryRate = money.FromMajor(100)
+
+const commissionPercent = 10
+
 type OrderInfo struct {
-	ID         int64   `json:"id"`
-	TotalPrice float64 `json:"total_price"`
+	ID         int64       `json:"id"`
+	TotalPrice money.Money `json:"total_price"`
 }
 
 type Summary struct {
-	TotalOrders   int     `json:"total_orders"`
-	TotalEarnings float64 `json:"total_earnings"`
+	TotalOrders   int         `json:"total_orders"`
+	TotalEarnings money.Money `json:"total_earnings"` // вместе с чаевыми
+	TotalTips     money.Money `json:"total_tips"`     // чаевые клиентов, достаются курьеру целиком
+	// Средняя оценка клиентов по отзывам, которые не скрыл модератор; 0 — оценок еще нет
+	Rating  float64 `json:"rating"`
+	Ratings int     `json:"ratings"`
 }
 
 type CourierInfo struct {
@@ -22,11 +35,17 @@ type CourierInfo struct {
 	Name string `json:"name"`
 }
 
+// ErrNotCourier — у пользователя из токена нет профиля курьера
+var ErrNotCourier = errors.New("пользователь не зарегистрирован как курьер")
+
 type Repository interface {
 	GetNewOrders(ctx context.Context) ([]OrderInfo, error)
+	// CourierByUser находит курьера по пользователю из токена (couriers.user_id)
+	CourierByUser(ctx context.Context, userID int64) (int64, error)
 	// Важно: здесь (string, error)
 	AcceptOrder(ctx context.Context, courierID, orderID int64) (string, error)
-	UpdateStatus(ctx context.Context, orderID int64, status string) error
+	// UpdateStatus меняет статус от имени курьера; чужой заказ менять нельзя
+	UpdateStatus(ctx context.Context, courierID, orderID int64, to status.Status) error
 	GetCourierHistory(ctx context.Context, courierID int64) ([]OrderInfo, error)
 	GetCourierSummary(ctx context.Context, courierID int64) (Summary, error)
 	GetAvailableCouriers(ctx context.Context) ([]CourierInfo, error)
@@ -59,11 +78,31 @@ func (r *pgRepo) GetNewOrders(ctx context.Context) ([]OrderInfo, error) {
 	return orders, nil
 }
 
+func (r *pgRepo) CourierByUser(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, "SELECT id FROM couriers WHERE user_id = $1", userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotCourier
+	}
+	return id, err
+}
+
 // Проверь, чтобы заголовок этой функции в точности совпадал с интерфейсом выше!
 func (r *pgRepo) AcceptOrder(ctx context.Context, courierID, orderID int64) (string, error) {
-	// 1. Пытаемся закрепить курьера за заказом
-	queryUpdate := "UPDATE orders SET courier_id = $1, status = 'accepted' WHERE id = $2 AND courier_id IS NULL"
-	result, err := r.db.Exec(ctx, queryUpdate, courierID, orderID)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Переводим заказ в accepted: машина состояний не даст взять отмененный или уже принятый заказ
+	if _, err := status.Apply(ctx, tx, orderID, status.Accepted, status.Actor{Type: status.ActorCourier, ID: courierID}, ""); err != nil {
+		return "", err
+	}
+
+	// 2. Закрепляем курьера за заказом
+	queryUpdate := "UPDATE orders SET courier_id = $1 WHERE id = $2 AND courier_id IS NULL"
+	result, err := tx.Exec(ctx, queryUpdate, courierID, orderID)
 	if err != nil {
 		return "", err
 	}
@@ -73,21 +112,48 @@ func (r *pgRepo) AcceptOrder(ctx context.Context, courierID, orderID int64) (str
 		return "", fmt.Errorf("заказ уже занят другим курьером")
 	}
 
-	// 2. Достаем имя курьера, чтобы вернуть его в ответе
+	// 3. Курьер занят, пока не доставит заказ или пока заказ не отменят.
+	// Заодно достаем его имя, чтобы вернуть в ответе
 	var courierName string
-	queryName := "SELECT name FROM couriers WHERE id = $1"
-	err = r.db.QueryRow(ctx, queryName, courierID).Scan(&courierName)
+	queryName := "UPDATE couriers SET is_available = FALSE WHERE id = $1 RETURNING name"
+	err = tx.QueryRow(ctx, queryName, courierID).Scan(&courierName)
 	if err != nil {
 		return "", err
 	}
 
-	return courierName, nil
+	return courierName, tx.Commit(ctx)
 }
 
-func (r *pgRepo) UpdateStatus(ctx context.Context, orderID int64, status string) error {
-	query := "UPDATE orders SET status = $1 WHERE id = $2"
-	_, err := r.db.Exec(ctx, query, status, orderID)
-	return err
+func (r *pgRepo) UpdateStatus(ctx context.Context, courierID, orderID int64, to status.Status) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Статус меняет только курьер, который везет заказ; строку блокируем до конца транзакции
+	var assigned *int64
+	err = tx.QueryRow(ctx, "SELECT courier_id FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&assigned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return err
+	}
+	if assigned == nil || *assigned != courierID {
+		return fmt.Errorf("%w: заказ %d назначен другому курьеру", status.ErrNotAllowed, orderID)
+	}
+
+	if _, err := status.Apply(ctx, tx, orderID, to, status.Actor{Type: status.ActorCourier, ID: courierID}, ""); err != nil {
+		return err
+	}
+	// Заказ закрыт — курьер снова может брать новые
+	if to == status.Completed || to == status.Failed {
+		if _, err := tx.Exec(ctx, "UPDATE couriers SET is_available = TRUE WHERE id = $1", courierID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *pgRepo) GetCourierHistory(ctx context.Context, courierID int64) ([]OrderInfo, error) {
@@ -108,16 +174,42 @@ func (r *pgRepo) GetCourierHistory(ctx context.Context, courierID int64) ([]Orde
 }
 
 func (r *pgRepo) GetCourierSummary(ctx context.Context, courierID int64) (Summary, error) {
-	var s Summary
-	// Считаем: (количество заказов * 100р) + (10% от суммы всех заказов)
-	query := `
-		SELECT
-			COUNT(id),
-			COALESCE(SUM(total_price * 0.1 + 100), 0)
-		FROM orders
-		WHERE courier_id = $1 AND status = 'completed'`
-
-	err := r.db.QueryRow(ctx, query, courierID).Scan(&s.TotalOrders, &s.TotalEarnings)
+	s := Summary{TotalEarnings: money.Zero(), TotalTips: money.Zero()}
+	// Считаем в Go, а не в SQL, чтобы округление было по правилам пакета money:
+	// за каждый заказ 100р + 10% от его суммы
+	query := "SELECT total_price FROM orders WHERE courier_id = $1 AND status = 'completed'"
+	rows, err := r.db.Query(ctx, query, courierID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var total money.Money
+		if err := rows.Scan(&total); err != nil {
+			return s, err
+		}
+		s.TotalOrders++
+		s.TotalEarnings = s.TotalEarnings.Add(deliveryRate).Add(total.Percent(commissionPercent))
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	// Чаевые засчитываются, когда деньги списаны: при оформлении — после доставки, после доставки — сразу
+	query = `
+		SELECT COALESCE(SUM(t.amount), 0) FROM tips t
+		JOIN orders o ON o.id = t.order_id
+		WHERE o.courier_id = $1 AND t.status = 'paid'`
+	if err := r.db.QueryRow(ctx, query, courierID).Scan(&s.TotalTips); err != nil {
+		return s, err
+	}
+	s.TotalEarnings = s.TotalEarnings.Add(s.TotalTips)
+
+	query = `
+		SELECT COALESCE(ROUND(AVG(courier_score), 2), 0)::float8, COUNT(*) FROM reviews
+		WHERE courier_id = $1 AND courier_score IS NOT NULL AND NOT hidden`
+	err = r.db.QueryRow(ctx, query, courierID).Scan(&s.Rating, &s.Ratings)
 	return s, err
 }
 
@@ -2,16 +2,16 @@ package service
 
 import (
 	"context"
-	"log"
 
 	"github.com/JuniorCrafter/fooddelivery/internal/courier/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
 )
 
 type Service interface {
 	FindWork(ctx context.Context) ([]repo.OrderInfo, error)
-	// Тут тоже добавляем string в возвращаемые значения
-	TakeOrder(ctx context.Context, courierID, orderID int64) (string, error)
-	ChangeStatus(ctx context.Context, orderID int64, status string) error // Новое!
+	// TakeOrder и ChangeStatus получают пользователя из токена, а курьера находят по нему
+	TakeOrder(ctx context.Context, userID, orderID int64) (string, error)
+	ChangeStatus(ctx context.Context, userID, orderID int64, status string) error
 	GetDashboard(ctx context.Context, courierID int64) (repo.Summary, []repo.OrderInfo, error)
 	ListFreeCouriers(ctx context.Context) ([]repo.CourierInfo, error)
 }
@@ -28,22 +28,27 @@ func (s *courierService) FindWork(ctx context.Context) ([]repo.OrderInfo, error)
 	return s.repo.GetNewOrders(ctx)
 }
 
-func (s *courierService) TakeOrder(ctx context.Context, courierID, orderID int64) (string, error) {
+func (s *courierService) TakeOrder(ctx context.Context, userID, orderID int64) (string, error) {
+	courierID, err := s.repo.CourierByUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
 	return s.repo.AcceptOrder(ctx, courierID, orderID)
 }
 
-func (s *courierService) ChangeStatus(ctx context.Context, orderID int64, status string) error {
-	// 1. Обновляем в базе (PostgreSQL), как и раньше
-	err := s.repo.UpdateStatus(ctx, orderID, status)
+func (s *courierService) ChangeStatus(ctx context.Context, userID, orderID int64, newStatus string) error {
+	to, err := status.Parse(newStatus)
+	if err != nil {
+		return err
+	}
+	courierID, err := s.repo.CourierByUser(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	// 2. Публикуем событие в RabbitMQ (в будущем вынесем в отдельный репозиторий)
-	// Пока для теста просто логируем, но в коде здесь будет вызов Publish()
-	log.Printf(" Статус заказа %d изменен на %s. Событие отправлено в очередь.", orderID, status)
-
-	return nil
+	// Машина состояний заказа пишет order.status_changed в outbox той же транзакцией,
+	// а в RabbitMQ его отправит relay сервиса заказов
+	return s.repo.UpdateStatus(ctx, courierID, orderID, to)
 }
 
 func (s *courierService) GetDashboard(ctx context.Context, courierID int64) (repo.Summary, []repo.OrderInfo, error) {
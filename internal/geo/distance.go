@@ -0,0 +1,31 @@
+// Package geo — общая геометрия для сервисов: расстояние между точками на карте.
+package geo
+
+import "math"
+
+// earthRadiusKm — средний радиус Земли
+const earthRadiusKm = 6371
+
+// Point — координаты в градусах
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Valid — широта и долгота в допустимых пределах
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Distance считает расстояние в километрах по формуле гаверсинуса (по прямой, не по дорогам)
+func Distance(a, b Point) float64 {
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*math.Pi/180)*math.Cos(b.Lat*math.Pi/180)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	c := 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+	return earthRadiusKm * c
+}
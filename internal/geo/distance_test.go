@@ -0,0 +1,51 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64 // км
+		tol  float64
+	}{
+		{"одна точка", Point{55.7558, 37.6173}, Point{55.7558, 37.6173}, 0, 1e-9},
+		{"четверть экватора", Point{0, 0}, Point{0, 90}, math.Pi * earthRadiusKm / 2, 1e-6},
+		{"от полюса до полюса", Point{90, 0}, Point{-90, 0}, math.Pi * earthRadiusKm, 1e-6},
+		{"Москва — Санкт-Петербург", Point{55.7558, 37.6173}, Point{59.9343, 30.3351}, 634, 2},
+		{"через антимеридиан", Point{0, 179.5}, Point{0, -179.5}, 111.2, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.a, tt.b)
+			if math.Abs(got-tt.want) > tt.tol {
+				t.Fatalf("Distance() = %.3f, ожидали %.3f ± %.3f", got, tt.want, tt.tol)
+			}
+			if back := Distance(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Fatalf("расстояние несимметрично: %.6f и %.6f", got, back)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		p  Point
+		ok bool
+	}{
+		{Point{55.75, 37.61}, true},
+		{Point{-90, -180}, true},
+		{Point{90, 180}, true},
+		{Point{90.1, 0}, false},
+		{Point{0, -180.1}, false},
+		{Point{math.NaN(), 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Valid(); got != tt.ok {
+			t.Errorf("%+v.Valid() = %v, ожидали %v", tt.p, got, tt.ok)
+		}
+	}
+}
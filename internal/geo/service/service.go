@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
-	"math"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/geo"
 	"github.com/JuniorCrafter/fooddelivery/internal/geo/repo"
 )
 
@@ -30,16 +30,5 @@ func (s *geoService) GetDistance(ctx context.Context, courierID string, destLat,
 	if err != nil {
 		return 0, err
 	}
-
-	// Математика Гаверсинуса
-	const R = 6371 // Радиус Земли в км
-	dLat := (destLat - loc.Latitude) * math.Pi / 180
-	dLon := (destLon - loc.Longitude) * math.Pi / 180
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(loc.Latitude*math.Pi/180)*math.Cos(destLat*math.Pi/180)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return R * c, nil
+	return geo.Distance(geo.Point{Lat: loc.Latitude, Lon: loc.Longitude}, geo.Point{Lat: destLat, Lon: destLon}), nil
 }
@@ -1,11 +1,18 @@
 package service
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/order/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/outbox"
 	"github.com/rabbitmq/amqp091-go"
 )
 
+// queueName — очередь уведомлений, подписанная на все события заказов
+const queueName = "order_status_updates"
+
 type NotificationConsumer struct {
 	conn *amqp091.Connection
 }
@@ -18,23 +25,73 @@ func NewConsumer(url string) (*NotificationConsumer, error) {
 	return &NotificationConsumer{conn: conn}, nil
 }
 
-// Listen начинает слушать очередь "order_status_updates"
-func (c *NotificationConsumer) Listen() {
-	ch, _ := c.conn.Channel()
+// Listen начинает слушать очередь "order_status_updates", привязанную к exchange order.events.
+// Возвращает ошибку, если подписаться не удалось или соединение с RabbitMQ оборвалось.
+func (c *NotificationConsumer) Listen() error {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return err
+	}
 	defer ch.Close()
 
-	// Объявляем очередь (если её нет, RabbitMQ её создаст)
-	q, _ := ch.QueueDeclare("order_status_updates", true, false, false, false, nil)
+	// Объявляем exchange и очередь (если их нет, RabbitMQ их создаст) и связываем их
+	if err := ch.ExchangeDeclare(events.Exchange, "topic", true, false, false, false, nil); err != nil {
+		return err
+	}
+	q, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	if err := ch.QueueBind(q.Name, "order.#", events.Exchange, false, nil); err != nil {
+		return err
+	}
+	// Одна очередь и один потребитель с prefetch 1 — события заказа обрабатываются в порядке публикации
+	if err := ch.Qos(1, 0, false); err != nil {
+		return err
+	}
 
-	msgs, _ := ch.Consume(q.Name, "", true, false, false, false, nil)
+	msgs, err := ch.Consume(q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		for d := range msgs {
-			// Имитация отправки уведомления
-			log.Printf(" Получено событие: %s. Отправляем Push клиенту...", d.Body)
+	log.Println("Notification Service ждет сообщений...")
+	for d := range msgs {
+		if err := handle(d.Body); err != nil {
+			// Битое сообщение повтор не починит — не возвращаем его в очередь
+			log.Printf("Не удалось разобрать событие %s: %v", d.MessageId, err)
+			d.Nack(false, false)
+			continue
 		}
-	}()
+		d.Ack(false)
+	}
+	return fmt.Errorf("соединение с RabbitMQ закрыто")
+}
 
-	log.Println("Notification Service ждет сообщений...")
-	select {} // Вечный цикл, чтобы сервис не закрылся
+func handle(body []byte) error {
+	var e outbox.Envelope
+	if err := json.Unmarshal(body, &e); err != nil {
+		return err
+	}
+	switch e.Type {
+	case events.OrderStatusChanged:
+		var data events.OrderStatusChangedData
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			return err
+		}
+		// Имитация отправки уведомления
+		log.Printf(" Заказ %d: %s. Отправляем Push клиенту %d...", data.OrderID, data.To, data.UserID)
+	case events.OrderCancelled:
+		var data events.OrderCancelledData
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			return err
+		}
+		log.Printf(" Заказ %d отменен: %s. Отправляем Push клиенту %d...", data.OrderID, data.Reason, data.UserID)
+		if data.CourierID != 0 {
+			log.Printf(" Отправляем Push курьеру %d: заказ %d отменен", data.CourierID, data.OrderID)
+		}
+	default:
+		// Новые типы событий старый сервис просто пропускает
+	}
+	return nil
 }
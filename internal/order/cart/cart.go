@@ -0,0 +1,220 @@
+// Package cart — корзина покупателя в Redis. Корзина хранит только что и сколько
+// выбрано: цены, названия и доступность сервис каждый раз берет из каталога заново.
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MaxLines    = 50 // разных позиций в корзине
+	MaxQuantity = 99 // штук одной позиции
+)
+
+var (
+	ErrLineNotFound = errors.New("позиции нет в корзине")
+	ErrTooManyLines = fmt.Errorf("в корзине не больше %d позиций", MaxLines)
+	ErrTooMany      = fmt.Errorf("одной позиции можно положить не больше %d штук", MaxQuantity)
+)
+
+// Line — позиция корзины: товар с набором опций. Один товар с разными опциями — разные позиции.
+type Line struct {
+	ProductID int64
+	OptionIDs []int64 // по возрастанию
+	Quantity  int
+}
+
+// NewLine собирает позицию; порядок опций в запросе на позицию не влияет
+func NewLine(productID int64, optionIDs []int64, quantity int) Line {
+	ids := slices.Clone(optionIDs)
+	slices.Sort(ids)
+	return Line{ProductID: productID, OptionIDs: ids, Quantity: quantity}
+}
+
+// ID — идентификатор позиции в API: "12" или "12:3,5" (товар и опции)
+func (l Line) ID() string {
+	if len(l.OptionIDs) == 0 {
+		return strconv.FormatInt(l.ProductID, 10)
+	}
+	opts := make([]string, len(l.OptionIDs))
+	for i, id := range l.OptionIDs {
+		opts[i] = strconv.FormatInt(id, 10)
+	}
+	return strconv.FormatInt(l.ProductID, 10) + ":" + strings.Join(opts, ",")
+}
+
+// ParseID разбирает идентификатор позиции обратно в товар и опции
+func ParseID(id string) (Line, error) {
+	product, options, _ := strings.Cut(id, ":")
+	productID, err := strconv.ParseInt(product, 10, 64)
+	if err != nil || productID <= 0 {
+		return Line{}, fmt.Errorf("%w: %q", ErrLineNotFound, id)
+	}
+	var optionIDs []int64
+	if options != "" {
+		for _, part := range strings.Split(options, ",") {
+			optionID, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || optionID <= 0 {
+				return Line{}, fmt.Errorf("%w: %q", ErrLineNotFound, id)
+			}
+			optionIDs = append(optionIDs, optionID)
+		}
+	}
+	line := NewLine(productID, optionIDs, 0)
+	if line.ID() != id {
+		return Line{}, fmt.Errorf("%w: %q", ErrLineNotFound, id)
+	}
+	return line, nil
+}
+
+// Checkout — последнее оформление корзины: по нему повтор запроса с тем же
+// Idempotency-Key находит заказ, хотя корзина уже пуста
+type Checkout struct {
+	Key     string
+	OrderID int64
+}
+
+type Store interface {
+	// Get отдает позиции по порядку идентификаторов и продлевает жизнь корзины
+	Get(ctx context.Context, userID int64) ([]Line, error)
+	// Add кладет позицию; если такая уже есть, прибавляет количество
+	Add(ctx context.Context, userID int64, line Line) error
+	// SetQuantity меняет количество существующей позиции
+	SetQuantity(ctx context.Context, userID int64, lineID string, quantity int) error
+	Remove(ctx context.Context, userID int64, lineID string) error
+	Clear(ctx context.Context, userID int64) error
+	// CheckedOut очищает корзину после оформления заказа и запоминает оформление
+	CheckedOut(ctx context.Context, userID int64, c Checkout) error
+	// LastCheckout — последнее оформление; пустой Key — оформлений не было или они истекли
+	LastCheckout(ctx context.Context, userID int64) (Checkout, error)
+}
+
+// Корзина — хэш "идентификатор позиции -> количество". Лимиты проверяются скриптом,
+// чтобы параллельные запросы из двух вкладок не обошли их.
+var (
+	addScript = redis.NewScript(`
+		local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+		if current == 0 and redis.call('HLEN', KEYS[1]) >= tonumber(ARGV[4]) then return -1 end
+		local quantity = current + tonumber(ARGV[2])
+		if quantity > tonumber(ARGV[5]) then return -2 end
+		redis.call('HSET', KEYS[1], ARGV[1], quantity)
+		redis.call('PEXPIRE', KEYS[1], ARGV[3])
+		return quantity`)
+	setScript = redis.NewScript(`
+		if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then return 0 end
+		redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+		redis.call('PEXPIRE', KEYS[1], ARGV[3])
+		return 1`)
+)
+
+type redisStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewStore — корзины в Redis; брошенная корзина удаляется через ttl после последнего изменения или просмотра
+func NewStore(rdb *redis.Client, ttl time.Duration) Store {
+	return &redisStore{rdb: rdb, ttl: ttl}
+}
+
+func cartKey(userID int64) string     { return fmt.Sprintf("cart:%d", userID) }
+func checkoutKey(userID int64) string { return fmt.Sprintf("cart:%d:checkout", userID) }
+
+func (s *redisStore) Get(ctx context.Context, userID int64) ([]Line, error) {
+	key := cartKey(userID)
+	fields, err := s.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if err := s.rdb.PExpire(ctx, key, s.ttl).Err(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]Line, 0, len(fields))
+	for id, raw := range fields {
+		line, err := ParseID(id)
+		if err != nil {
+			return nil, err
+		}
+		if line.Quantity, err = strconv.Atoi(raw); err != nil {
+			return nil, fmt.Errorf("корзина %d: количество %q у позиции %s", userID, raw, id)
+		}
+		lines = append(lines, line)
+	}
+	slices.SortFunc(lines, func(a, b Line) int { return strings.Compare(a.ID(), b.ID()) })
+	return lines, nil
+}
+
+func (s *redisStore) Add(ctx context.Context, userID int64, line Line) error {
+	res, err := addScript.Run(ctx, s.rdb, []string{cartKey(userID)},
+		line.ID(), line.Quantity, s.ttl.Milliseconds(), MaxLines, MaxQuantity).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case -1:
+		return ErrTooManyLines
+	case -2:
+		return ErrTooMany
+	}
+	return nil
+}
+
+func (s *redisStore) SetQuantity(ctx context.Context, userID int64, lineID string, quantity int) error {
+	res, err := setScript.Run(ctx, s.rdb, []string{cartKey(userID)}, lineID, quantity, s.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+	}
+	return nil
+}
+
+func (s *redisStore) Remove(ctx context.Context, userID int64, lineID string) error {
+	n, err := s.rdb.HDel(ctx, cartKey(userID), lineID).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+	}
+	return nil
+}
+
+func (s *redisStore) Clear(ctx context.Context, userID int64) error {
+	return s.rdb.Del(ctx, cartKey(userID)).Err()
+}
+
+func (s *redisStore) CheckedOut(ctx context.Context, userID int64, c Checkout) error {
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, cartKey(userID))
+		pipe.HSet(ctx, checkoutKey(userID), "key", c.Key, "order_id", c.OrderID)
+		pipe.PExpire(ctx, checkoutKey(userID), s.ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) LastCheckout(ctx context.Context, userID int64) (Checkout, error) {
+	fields, err := s.rdb.HGetAll(ctx, checkoutKey(userID)).Result()
+	if err != nil || len(fields) == 0 {
+		return Checkout{}, err
+	}
+	orderID, err := strconv.ParseInt(fields["order_id"], 10, 64)
+	if err != nil {
+		return Checkout{}, fmt.Errorf("корзина %d: order_id %q последнего оформления", userID, fields["order_id"])
+	}
+	return Checkout{Key: fields["key"], OrderID: orderID}, nil
+}
@@ -0,0 +1,108 @@
+// Package delivery — стоимость доставки по расстоянию от кухни до клиента.
+//
+// Тарифы задаются ступенями "до N км — столько-то рублей" (DELIVERY_FEE_TIERS="3:99,7:149,15:249"):
+// берется первая ступень, в которую попадает расстояние; дальше последней ступени не возим.
+// От суммы DELIVERY_FREE_FROM доставка бесплатная, меньше DELIVERY_MIN_BASKET заказ не принимается.
+// Суммы сравниваются со стоимостью еды уже со скидкой по промокоду.
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+var (
+	ErrInvalidConfig = errors.New("некорректные тарифы доставки")
+	ErrTooFar        = errors.New("адрес доставки слишком далеко от кухни")
+	ErrMinBasket     = errors.New("сумма заказа меньше минимальной для доставки")
+)
+
+// Tier — ступень тарифа: доставка не дальше UpToKm стоит Fee
+type Tier struct {
+	UpToKm float64     `json:"up_to_km"`
+	Fee    money.Money `json:"fee"`
+}
+
+// Pricing — тарифы доставки; нулевые FreeFrom и MinBasket — порога нет
+type Pricing struct {
+	Tiers     []Tier
+	FreeFrom  money.Money
+	MinBasket money.Money
+}
+
+// Quote — расчет доставки для конкретного заказа
+type Quote struct {
+	DistanceKm float64     `json:"distance_km"`
+	Fee        money.Money `json:"fee"`
+	FreeFrom   money.Money `json:"free_from"` // с этой суммы доставка бесплатная, 0 — порога нет
+	MinBasket  money.Money `json:"min_basket"`
+}
+
+// NewPricing разбирает тарифы из конфига: tiers — "км:рубли" через запятую, суммы — в рублях.
+// Пустые freeFrom и minBasket — порога нет.
+func NewPricing(tiers, freeFrom, minBasket string) (Pricing, error) {
+	var p Pricing
+	for _, part := range strings.Split(tiers, ",") {
+		km, fee, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return Pricing{}, fmt.Errorf("%w: ступень %q, нужно км:рубли", ErrInvalidConfig, part)
+		}
+		var t Tier
+		var err error
+		if t.UpToKm, err = strconv.ParseFloat(strings.TrimSpace(km), 64); err != nil || t.UpToKm <= 0 || math.IsInf(t.UpToKm, 0) {
+			return Pricing{}, fmt.Errorf("%w: расстояние %q", ErrInvalidConfig, km)
+		}
+		if t.Fee, err = money.Parse(strings.TrimSpace(fee)); err != nil || t.Fee.IsNegative() {
+			return Pricing{}, fmt.Errorf("%w: цена %q", ErrInvalidConfig, fee)
+		}
+		p.Tiers = append(p.Tiers, t)
+	}
+	sort.Slice(p.Tiers, func(i, j int) bool { return p.Tiers[i].UpToKm < p.Tiers[j].UpToKm })
+
+	for _, v := range []struct {
+		raw string
+		dst *money.Money
+	}{{freeFrom, &p.FreeFrom}, {minBasket, &p.MinBasket}} {
+		*v.dst = money.Zero()
+		if strings.TrimSpace(v.raw) == "" {
+			continue
+		}
+		amount, err := money.Parse(strings.TrimSpace(v.raw))
+		if err != nil || amount.IsNegative() {
+			return Pricing{}, fmt.Errorf("%w: сумма %q", ErrInvalidConfig, v.raw)
+		}
+		*v.dst = amount
+	}
+	return p, nil
+}
+
+// Quote считает доставку на расстояние distanceKm для еды на сумму basket
+func (p Pricing) Quote(distanceKm float64, basket money.Money) (Quote, error) {
+	q := Quote{DistanceKm: math.Round(distanceKm*10) / 10, FreeFrom: p.FreeFrom, MinBasket: p.MinBasket}
+	if basket.Cmp(p.MinBasket) < 0 {
+		return q, fmt.Errorf("%w: нужно от %s", ErrMinBasket, p.MinBasket)
+	}
+
+	tier := -1
+	for i, t := range p.Tiers {
+		if distanceKm <= t.UpToKm {
+			tier = i
+			break
+		}
+	}
+	if tier < 0 {
+		return q, fmt.Errorf("%w: %.1f км, возим до %.1f км", ErrTooFar, distanceKm, p.Tiers[len(p.Tiers)-1].UpToKm)
+	}
+
+	q.Fee = p.Tiers[tier].Fee
+	if p.FreeFrom.IsPositive() && basket.Cmp(p.FreeFrom) >= 0 {
+		q.Fee = money.Zero()
+	}
+	return q, nil
+}
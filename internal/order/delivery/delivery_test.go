@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+func TestNewPricing(t *testing.T) {
+	p, err := NewPricing("7:149, 3:99,15:249.50", "1500", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tiers) != 3 || p.Tiers[0].UpToKm != 3 || p.Tiers[2].Fee != money.FromMinor(24950) {
+		t.Fatalf("ступени не отсортированы или разобраны неверно: %+v", p.Tiers)
+	}
+	if p.FreeFrom != money.FromMajor(1500) || !p.MinBasket.IsZero() {
+		t.Fatalf("пороги: %+v %+v", p.FreeFrom, p.MinBasket)
+	}
+
+	for _, tt := range []struct{ tiers, free, min string }{
+		{"", "", ""},
+		{"3", "", ""},
+		{"0:99", "", ""},
+		{"-1:99", "", ""},
+		{"Inf:99", "", ""},
+		{"3:-99", "", ""},
+		{"3:abc", "", ""},
+		{"3:99", "-1", ""},
+		{"3:99", "", "много"},
+	} {
+		if _, err := NewPricing(tt.tiers, tt.free, tt.min); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("NewPricing(%q, %q, %q) = %v, ожидали ErrInvalidConfig", tt.tiers, tt.free, tt.min, err)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	p, err := NewPricing("3:99,7:149,15:249", "1500", "500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		km       float64
+		basket   int64 // рубли
+		fee      int64 // рубли
+		err      error
+		distance float64
+	}{
+		{"рядом", 1.24, 800, 99, nil, 1.2},
+		{"ровно на границе ступени", 3, 800, 99, nil, 3},
+		{"чуть дальше границы", 3.01, 800, 149, nil, 3},
+		{"последняя ступень", 15, 800, 249, nil, 15},
+		{"бесплатно от порога", 10, 1500, 0, nil, 10},
+		{"дальше последней ступени", 15.01, 800, 0, ErrTooFar, 15},
+		{"меньше минимальной суммы", 1, 499, 0, ErrMinBasket, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := p.Quote(tt.km, money.FromMajor(tt.basket))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Quote() ошибка = %v, ожидали %v", err, tt.err)
+			}
+			if q.DistanceKm != tt.distance {
+				t.Fatalf("DistanceKm = %v, ожидали %v (округление до 0.1 км)", q.DistanceKm, tt.distance)
+			}
+			if err == nil && q.Fee != money.FromMajor(tt.fee) {
+				t.Fatalf("Fee = %v, ожидали %d", q.Fee, tt.fee)
+			}
+		})
+	}
+
+	// Без порогов доставка всегда платная, а минимальной суммы нет
+	p, _ = NewPricing("5:199", "", "")
+	if q, err := p.Quote(4, money.FromMajor(100000)); err != nil || q.Fee != money.FromMajor(199) {
+		t.Fatalf("Quote() без порогов = %+v, %v", q, err)
+	}
+}
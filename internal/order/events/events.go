@@ -0,0 +1,81 @@
+// Package events — события жизненного цикла заказов для других сервисов
+// (курьеры, уведомления, оплата). Публикуются через transactional outbox
+// (internal/platform/outbox): таблица order_outbox, exchange order.events, routing key = type.
+// Пишут их и сервис заказов, и сервис курьеров — в транзакции смены статуса;
+// публикует relay сервиса заказов. События одного заказа уходят строго по порядку.
+//
+//	{"id": 42, "type": "order.status_changed", "version": 1, "occurred_at": "...", "data": {...}}
+//
+// data по типам (версия 1):
+//   - order.status_changed — {"order_id", "user_id", "from_status", "to_status", "actor": {"type", "id"}, "reason", "courier_id"}
+//     на каждый переход, включая создание заказа (from_status пустой, to_status = "awaiting_payment").
+//     Кухне заказ уходит после оплаты: awaiting_payment -> new, или -> scheduled у заказа ко времени
+//     (тогда new придет позже, когда подойдет время готовки). Переходы после оплаты и выпуска
+//     делает система: actor.type = "system", reason — причина перехода.
+//   - order.cancelled — {"order_id", "user_id", "from_status", "actor": {"type", "id"}, "reason", "free", "courier_id"}
+//     free = true, если заказ отменили до начала готовки: деньги возвращаются полностью.
+//     courier_id есть, только если на заказ уже был назначен курьер; он снова свободен.
+//     Приходит после order.status_changed того же перехода.
+//
+// Пакет не зависит от internal/order/status: статусы здесь — просто строки.
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/outbox"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Exchange — topic-exchange событий заказов; подписка, например, на "order.*"
+	Exchange = "order.events"
+	// SchemaVersion — текущая версия схемы data
+	SchemaVersion = 1
+	// Table — outbox заказов (db/init/011_order_cancellation.sql)
+	Table = "order_outbox"
+)
+
+const (
+	OrderStatusChanged = "order.status_changed"
+	OrderCancelled     = "order.cancelled"
+)
+
+// Actor — кто совершил переход (см. status.Actor)
+type Actor struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id,omitempty"`
+}
+
+type OrderStatusChangedData struct {
+	OrderID   int64  `json:"order_id"`
+	UserID    int64  `json:"user_id"`
+	From      string `json:"from_status"`
+	To        string `json:"to_status"`
+	Actor     Actor  `json:"actor"`
+	Reason    string `json:"reason,omitempty"`
+	CourierID int64  `json:"courier_id,omitempty"`
+}
+
+type OrderCancelledData struct {
+	OrderID   int64  `json:"order_id"`
+	UserID    int64  `json:"user_id"`
+	From      string `json:"from_status"`
+	Actor     Actor  `json:"actor"`
+	Reason    string `json:"reason"`
+	Free      bool   `json:"free"`
+	CourierID int64  `json:"courier_id,omitempty"`
+}
+
+// Enqueue записывает событие заказа orderID в outbox в транзакции изменения.
+// По orderID relay сохраняет порядок событий одного заказа.
+func Enqueue(ctx context.Context, tx outbox.Execer, orderID int64, eventType string, data any) error {
+	return outbox.Enqueue(ctx, tx, Table, fmt.Sprint(orderID), eventType, SchemaVersion, data)
+}
+
+// NewRelay — фоновая публикация событий заказов, запускается в cmd/order
+func NewRelay(db *pgxpool.Pool, url string, interval time.Duration) *outbox.Relay {
+	return outbox.NewRelay(db, url, interval, Table, Exchange)
+}
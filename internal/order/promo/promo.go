@@ -0,0 +1,192 @@
+// Package promo — промокоды и расчет скидки. Правила кода проверяются здесь без базы;
+// лимиты использований (общий, на пользователя, "только первый заказ") считаются
+// в транзакции создания заказа, см. repo.CreateOrder.
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+type Kind string
+
+const (
+	Percent Kind = "percent" // Percent% от суммы подходящих позиций
+	Fixed   Kind = "fixed"   // Amount рублей, но не больше суммы подходящих позиций
+)
+
+var (
+	ErrInvalid       = errors.New("некорректный промокод")
+	ErrDuplicate     = errors.New("такой промокод уже есть")
+	ErrNotFound      = errors.New("промокод не найден")
+	ErrInactive      = errors.New("промокод сейчас не действует")
+	ErrMinBasket     = errors.New("сумма заказа меньше минимальной для промокода")
+	ErrNotApplicable = errors.New("промокод не действует на товары в заказе")
+	ErrExhausted     = errors.New("промокод больше не действует: лимит использований исчерпан")
+	ErrUserLimit     = errors.New("вы уже использовали этот промокод максимальное число раз")
+	ErrFirstOrder    = errors.New("промокод действует только на первый заказ")
+)
+
+const maxCodeLen = 32
+
+// Code — промокод и его правила. Пустые ProductIDs и CategoryIDs — скидка на весь заказ,
+// иначе только на позиции из этих товаров или категорий.
+type Code struct {
+	ID             int64       `json:"id"`
+	Code           string      `json:"code"`
+	Kind           Kind        `json:"kind"`
+	Percent        int64       `json:"percent,omitempty"`
+	Amount         money.Money `json:"amount"`
+	MinBasket      money.Money `json:"min_basket"`
+	FirstOrderOnly bool        `json:"first_order_only"`
+	MaxUses        *int        `json:"max_uses,omitempty"`       // nil — без общего лимита
+	PerUserLimit   *int        `json:"per_user_limit,omitempty"` // nil — без лимита на пользователя
+	UsedCount      int         `json:"used_count"`
+	ValidFrom      *time.Time  `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time  `json:"valid_until,omitempty"`
+	ProductIDs     []int64     `json:"product_ids,omitempty"`
+	CategoryIDs    []int64     `json:"category_ids,omitempty"`
+	IsActive       bool        `json:"is_active"`
+}
+
+// Line — позиция заказа для расчета скидки: сумма уже с учетом количества и опций
+type Line struct {
+	ProductID  int64
+	CategoryID int64 // 0 — без категории
+	Amount     money.Money
+}
+
+// Normalize приводит код к виду, в котором он хранится: без пробелов по краям, в верхнем регистре
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate проверяет правила нового промокода и нормализует код
+func Validate(c *Code) error {
+	c.Code = Normalize(c.Code)
+	if c.Code == "" || len(c.Code) > maxCodeLen || strings.ContainsAny(c.Code, " \t\n") {
+		return fmt.Errorf("%w: код должен быть от 1 до %d символов без пробелов", ErrInvalid, maxCodeLen)
+	}
+	switch c.Kind {
+	case Percent:
+		if c.Percent < 1 || c.Percent > 100 {
+			return fmt.Errorf("%w: процент скидки должен быть от 1 до 100", ErrInvalid)
+		}
+		c.Amount = money.Zero()
+	case Fixed:
+		if !c.Amount.IsPositive() {
+			return fmt.Errorf("%w: сумма скидки должна быть больше нуля", ErrInvalid)
+		}
+		c.Percent = 0
+	default:
+		return fmt.Errorf("%w: тип скидки %q, нужен percent или fixed", ErrInvalid, c.Kind)
+	}
+	if c.MinBasket.IsNegative() {
+		return fmt.Errorf("%w: минимальная сумма заказа не может быть отрицательной", ErrInvalid)
+	}
+	if (c.MaxUses != nil && *c.MaxUses < 1) || (c.PerUserLimit != nil && *c.PerUserLimit < 1) {
+		return fmt.Errorf("%w: лимиты использований должны быть больше нуля", ErrInvalid)
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidUntil.After(*c.ValidFrom) {
+		return fmt.Errorf("%w: valid_until должен быть позже valid_from", ErrInvalid)
+	}
+	return nil
+}
+
+// Active — код включен и сейчас в окне действия
+func (c Code) Active(now time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	if c.ValidFrom != nil && now.Before(*c.ValidFrom) {
+		return false
+	}
+	return c.ValidUntil == nil || now.Before(*c.ValidUntil)
+}
+
+// Discount считает скидку на заказ из lines. Лимиты использований здесь не проверяются.
+func (c Code) Discount(lines []Line, now time.Time) (money.Money, error) {
+	if !c.Active(now) {
+		return money.Money{}, ErrInactive
+	}
+
+	basket, eligible := money.Zero(), money.Zero()
+	for _, l := range lines {
+		basket = basket.Add(l.Amount)
+		if c.applies(l) {
+			eligible = eligible.Add(l.Amount)
+		}
+	}
+	if basket.Cmp(c.MinBasket) < 0 {
+		return money.Money{}, fmt.Errorf("%w: нужно от %s", ErrMinBasket, c.MinBasket)
+	}
+	if !eligible.IsPositive() {
+		return money.Money{}, ErrNotApplicable
+	}
+
+	if c.Kind == Percent {
+		return eligible.Percent(c.Percent), nil
+	}
+	if c.Amount.Cmp(eligible) > 0 {
+		return eligible, nil
+	}
+	return c.Amount, nil
+}
+
+// Allocate раскладывает скидку discount по строкам lines пропорционально сумме тех, к которым код
+// применим, — так в чеке у каждой позиции своя цена со скидкой и свой НДС. Доли округляются вниз,
+// а оставшиеся копейки получают строки с самыми большими остатками, поэтому сумма долей всегда равна discount.
+func (c Code) Allocate(lines []Line, discount money.Money) []money.Money {
+	shares := make([]money.Money, len(lines))
+	var eligible int64
+	for i, l := range lines {
+		shares[i] = money.New(0, discount.Currency)
+		if c.applies(l) {
+			eligible += l.Amount.Amount
+		}
+	}
+	if eligible <= 0 {
+		return shares
+	}
+
+	remainders := make([]int64, len(lines))
+	var order []int
+	left := discount.Amount
+	for i, l := range lines {
+		if !c.applies(l) {
+			continue
+		}
+		product := discount.Amount * l.Amount.Amount
+		shares[i].Amount = product / eligible
+		remainders[i] = product % eligible
+		left -= shares[i].Amount
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:left] {
+		shares[i].Amount++
+	}
+	return shares
+}
+
+func (c Code) applies(l Line) bool {
+	if len(c.ProductIDs) == 0 && len(c.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == l.ProductID {
+			return true
+		}
+	}
+	for _, id := range c.CategoryIDs {
+		if l.CategoryID != 0 && id == l.CategoryID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package promo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+var now = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func rub(minor int64) money.Money { return money.FromMinor(minor) }
+
+func TestValidate(t *testing.T) {
+	zero, until := 0, now.Add(-time.Hour)
+	tests := []struct {
+		name string
+		c    Code
+		ok   bool
+	}{
+		{"процент", Code{Code: " spring10 ", Kind: Percent, Percent: 10}, true},
+		{"сумма", Code{Code: "MINUS200", Kind: Fixed, Amount: rub(20000)}, true},
+		{"пустой код", Code{Code: "  ", Kind: Percent, Percent: 10}, false},
+		{"пробел внутри", Code{Code: "A B", Kind: Percent, Percent: 10}, false},
+		{"слишком длинный", Code{Code: "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456", Kind: Percent, Percent: 10}, false},
+		{"101%", Code{Code: "X", Kind: Percent, Percent: 101}, false},
+		{"0%", Code{Code: "X", Kind: Percent}, false},
+		{"нулевая сумма", Code{Code: "X", Kind: Fixed}, false},
+		{"неизвестный тип", Code{Code: "X", Kind: "gift"}, false},
+		{"отрицательный минимум", Code{Code: "X", Kind: Percent, Percent: 5, MinBasket: rub(-1)}, false},
+		{"нулевой лимит", Code{Code: "X", Kind: Percent, Percent: 5, MaxUses: &zero}, false},
+		{"окно наоборот", Code{Code: "X", Kind: Percent, Percent: 5, ValidFrom: &now, ValidUntil: &until}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.c)
+			if tt.ok && err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Validate() = %v, ожидали ErrInvalid", err)
+			}
+		})
+	}
+
+	c := Code{Code: " spring10 ", Kind: Percent, Percent: 10, Amount: rub(500)}
+	if err := Validate(&c); err != nil || c.Code != "SPRING10" || !c.Amount.IsZero() {
+		t.Fatalf("Validate() не нормализовал код: %+v, %v", c, err)
+	}
+}
+
+func TestActive(t *testing.T) {
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name string
+		c    Code
+		ok   bool
+	}{
+		{"без окна", Code{IsActive: true}, true},
+		{"выключен", Code{}, false},
+		{"еще не начался", Code{IsActive: true, ValidFrom: &after}, false},
+		{"уже закончился", Code{IsActive: true, ValidUntil: &before}, false},
+		{"внутри окна", Code{IsActive: true, ValidFrom: &before, ValidUntil: &after}, true},
+		{"ровно на конце окна", Code{IsActive: true, ValidUntil: &now}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Active(now); got != tt.ok {
+			t.Errorf("%s: Active() = %v, ожидали %v", tt.name, got, tt.ok)
+		}
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	lines := []Line{
+		{ProductID: 1, CategoryID: 10, Amount: rub(50000)},
+		{ProductID: 2, CategoryID: 20, Amount: rub(30000)},
+		{ProductID: 3, Amount: rub(20000)},
+	}
+	tests := []struct {
+		name string
+		c    Code
+		want money.Money
+		err  error
+	}{
+		{"процент на весь заказ", Code{Kind: Percent, Percent: 15}, rub(15000), nil},
+		{"процент на товар", Code{Kind: Percent, Percent: 10, ProductIDs: []int64{2}}, rub(3000), nil},
+		{"процент на категорию", Code{Kind: Percent, Percent: 10, CategoryIDs: []int64{10, 20}}, rub(8000), nil},
+		{"сумма", Code{Kind: Fixed, Amount: rub(25000)}, rub(25000), nil},
+		{"сумма больше подходящих позиций", Code{Kind: Fixed, Amount: rub(100000), ProductIDs: []int64{3}}, rub(20000), nil},
+		{"минимальная корзина считается по всему заказу", Code{Kind: Percent, Percent: 10, MinBasket: rub(100000), ProductIDs: []int64{3}}, rub(2000), nil},
+		{"корзина меньше минимума", Code{Kind: Percent, Percent: 10, MinBasket: rub(100001)}, money.Money{}, ErrMinBasket},
+		{"нет подходящих позиций", Code{Kind: Percent, Percent: 10, ProductIDs: []int64{99}}, money.Money{}, ErrNotApplicable},
+		{"категория 0 не совпадает", Code{Kind: Percent, Percent: 10, CategoryIDs: []int64{0}}, money.Money{}, ErrNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.IsActive = true
+			got, err := tt.c.Discount(lines, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Discount() ошибка = %v, ожидали %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("Discount() = %v, ожидали %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Code{Kind: Percent, Percent: 10}).Discount(lines, now); !errors.Is(err, ErrInactive) {
+		t.Fatalf("выключенный код: %v", err)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Code
+		lines    []Line
+		discount int64
+		want     []int64
+	}{
+		{
+			"пропорционально суммам",
+			Code{},
+			[]Line{{ProductID: 1, Amount: rub(30000)}, {ProductID: 2, Amount: rub(10000)}},
+			4000,
+			[]int64{3000, 1000},
+		},
+		{
+			"копейки уходят строкам с большими остатками",
+			Code{},
+			[]Line{{ProductID: 1, Amount: rub(100)}, {ProductID: 2, Amount: rub(100)}, {ProductID: 3, Amount: rub(100)}},
+			100,
+			[]int64{34, 33, 33},
+		},
+		{
+			"неподходящие строки без скидки",
+			Code{ProductIDs: []int64{2, 3}},
+			[]Line{{ProductID: 1, Amount: rub(50000)}, {ProductID: 2, Amount: rub(200)}, {ProductID: 3, Amount: rub(100)}},
+			100,
+			[]int64{0, 67, 33},
+		},
+		{
+			"некуда раскладывать",
+			Code{ProductIDs: []int64{9}},
+			[]Line{{ProductID: 1, Amount: rub(100)}},
+			50,
+			[]int64{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := tt.c.Allocate(tt.lines, rub(tt.discount))
+			for i, s := range shares {
+				if s.Amount != tt.want[i] {
+					t.Fatalf("Allocate() = %v, ожидали %v", shares, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocateSumsToDiscount(t *testing.T) {
+	lines := []Line{{ProductID: 1, Amount: rub(12345)}, {ProductID: 2, Amount: rub(6789)}, {ProductID: 3, Amount: rub(1)}}
+	for discount := int64(0); discount <= 19135; discount += 997 {
+		var sum int64
+		for _, s := range (Code{}).Allocate(lines, rub(discount)) {
+			sum += s.Amount
+		}
+		if sum != discount {
+			t.Fatalf("скидка %d разложена в сумме на %d", discount, sum)
+		}
+	}
+}
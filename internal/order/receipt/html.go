@@ -0,0 +1,68 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+)
+
+// WriteHTML печатает чек страницей, которую можно открыть в браузере или сохранить в PDF через печать
+func (r Receipt) WriteHTML(w io.Writer) error {
+	return page.Execute(w, r)
+}
+
+var page = template.Must(template.New("receipt").Funcs(template.FuncMap{"rub": formatRub}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Чек № {{.Number}}</title>
+<style>
+	body { font-family: "Courier New", monospace; max-width: 640px; margin: 24px auto; color: #000; }
+	h1 { font-size: 18px; text-align: center; margin-bottom: 4px; }
+	.seller, .meta { text-align: center; font-size: 13px; }
+	table { width: 100%; border-collapse: collapse; margin-top: 16px; font-size: 13px; }
+	th, td { padding: 4px 2px; text-align: right; vertical-align: top; }
+	th:first-child, td:first-child { text-align: left; }
+	thead th { border-bottom: 1px dashed #000; }
+	tfoot td { border-top: 1px dashed #000; font-weight: bold; }
+	.taxes { margin-top: 12px; }
+	@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Кассовый чек. Приход</h1>
+<div class="seller">{{.Seller.Name}}{{with .Seller.INN}}, ИНН {{.}}{{end}}{{with .Seller.Address}}<br>{{.}}{{end}}</div>
+<div class="meta">Чек № {{.Number}} · заказ {{.OrderID}} · {{.IssuedAt.Format "02.01.2006 15:04"}}</div>
+<table>
+	<thead>
+		<tr><th>Наименование</th><th>Цена</th><th>Кол-во</th><th>Скидка</th><th>Сумма</th><th>НДС</th></tr>
+	</thead>
+	<tbody>
+	{{range .Lines}}
+		<tr>
+			<td>{{.Name}}</td>
+			<td>{{rub .Price}}</td>
+			<td>{{.Quantity}}</td>
+			<td>{{if .Discount.IsPositive}}−{{rub .Discount}}{{end}}</td>
+			<td>{{rub .Total}}</td>
+			<td>{{if .VATRate}}{{.VATRate}}%: {{rub .VAT}}{{else if eq .TaxCategory "none"}}без НДС{{else}}0%{{end}}</td>
+		</tr>
+	{{end}}
+	</tbody>
+	<tfoot>
+		<tr><td colspan="4">Итого</td><td colspan="2">{{rub .Total}}</td></tr>
+	</tfoot>
+</table>
+<table class="taxes">
+	<tr><td>Сумма без скидки</td><td>{{rub .Subtotal}}</td></tr>
+	{{if .Discount.IsPositive}}<tr><td>Скидка{{with .PromoCode}} по промокоду {{.}}{{end}}</td><td>−{{rub .Discount}}</td></tr>{{end}}
+	{{if .DeliveryFee.IsPositive}}<tr><td>Доставка</td><td>{{rub .DeliveryFee}}</td></tr>{{end}}
+	{{range .Taxes}}
+	<tr><td>{{if eq .TaxCategory "none"}}Без НДС{{else}}НДС {{.VATRate}}% с {{rub .Base}}{{end}}</td><td>{{if ne .TaxCategory "none"}}{{rub .VAT}}{{end}}</td></tr>
+	{{end}}
+	<tr><td>Всего НДС</td><td>{{rub .VATTotal}}</td></tr>
+	{{if .Tip.IsPositive}}<tr><td>Чаевые курьеру</td><td>{{rub .Tip}}</td></tr>{{end}}
+	<tr><td>Списано с карты</td><td>{{rub .Paid}}</td></tr>
+</table>
+</body>
+</html>
+`))
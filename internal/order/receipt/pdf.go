@@ -0,0 +1,308 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+)
+
+// Раскладка PDF-чека: лента шириной pdfColumns символов по центру листа A4
+const (
+	pdfColumns    = 64
+	pdfFontSize   = 9
+	pdfLeading    = 12
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMarginY    = 48
+)
+
+var monoFont = sync.OnceValues(func() (*ttFont, error) {
+	return parseTrueType(monoFontData)
+})
+
+// WritePDF печатает чек PDF-файлом: моноширинной лентой, как кассовый чек.
+// Шрифт вшивается в файл, поэтому кириллица читается в любом просмотрщике.
+func (r Receipt) WritePDF(w io.Writer) error {
+	font, err := monoFont()
+	if err != nil {
+		return err
+	}
+	return writePDF(w, font, fmt.Sprintf("Чек № %s", r.Number), r.textLines(pdfColumns))
+}
+
+// textLines — чек построчно шириной width символов; содержание то же, что у WriteHTML
+func (r Receipt) textLines(width int) []string {
+	var lines []string
+	add := func(l ...string) { lines = append(lines, l...) }
+	center := func(s string) {
+		for _, l := range wrap(s, width) {
+			add(strings.Repeat(" ", (width-utf8.RuneCountInString(l))/2) + l)
+		}
+	}
+	row := func(left, right string) {
+		add(columns(left, right, width)...)
+	}
+	separator := strings.Repeat("-", width)
+
+	center("Кассовый чек. Приход")
+	seller := r.Seller.Name
+	if r.Seller.INN != "" {
+		seller += ", ИНН " + r.Seller.INN
+	}
+	center(seller)
+	if r.Seller.Address != "" {
+		center(r.Seller.Address)
+	}
+	center(fmt.Sprintf("Чек № %s · заказ %d · %s", r.Number, r.OrderID, r.IssuedAt.Format("02.01.2006 15:04")))
+	add(separator)
+
+	for _, l := range r.Lines {
+		add(wrap(l.Name, width)...)
+		row(fmt.Sprintf("  %d x %s", l.Quantity, formatRub(l.Price)), formatRub(l.Total))
+		if l.Discount.IsPositive() {
+			row("  скидка", "−"+formatRub(l.Discount))
+		}
+		switch {
+		case l.VATRate != 0:
+			row(fmt.Sprintf("  НДС %d%%", l.VATRate), formatRub(l.VAT))
+		case l.TaxCategory == tax.NoVAT:
+			row("  без НДС", "")
+		default:
+			row("  НДС 0%", formatRub(l.VAT))
+		}
+	}
+	add(separator)
+	row("ИТОГО", formatRub(r.Total))
+	add(separator)
+
+	row("Сумма без скидки", formatRub(r.Subtotal))
+	if r.Discount.IsPositive() {
+		label := "Скидка"
+		if r.PromoCode != "" {
+			label += " по промокоду " + r.PromoCode
+		}
+		row(label, "−"+formatRub(r.Discount))
+	}
+	if r.DeliveryFee.IsPositive() {
+		row("Доставка", formatRub(r.DeliveryFee))
+	}
+	for _, t := range r.Taxes {
+		if t.TaxCategory == tax.NoVAT {
+			row("Без НДС", "")
+			continue
+		}
+		row(fmt.Sprintf("НДС %d%% с %s", t.VATRate, formatRub(t.Base)), formatRub(t.VAT))
+	}
+	row("Всего НДС", formatRub(r.VATTotal))
+	if r.Tip.IsPositive() {
+		row("Чаевые курьеру", formatRub(r.Tip))
+	}
+	row("Списано с карты", formatRub(r.Paid))
+	return lines
+}
+
+func formatRub(m money.Money) string {
+	return m.String() + " ₽"
+}
+
+// columns ставит left влево, а right вправо в строке шириной width.
+// Если вместе не помещаются, right уходит на следующую строку.
+func columns(left, right string, width int) []string {
+	gap := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if gap >= 1 {
+		return []string{left + strings.Repeat(" ", gap) + right}
+	}
+	lines := wrap(left, width)
+	if right != "" {
+		lines = append(lines, strings.Repeat(" ", max(width-utf8.RuneCountInString(right), 0))+right)
+	}
+	return lines
+}
+
+// wrap разбивает текст на строки не длиннее width символов по пробелам;
+// слишком длинное слово режется посередине
+func wrap(s string, width int) []string {
+	var lines []string
+	var cur []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(cur) > 0 && len(cur)+1+len(w) > width {
+			lines = append(lines, string(cur))
+			cur = cur[:0]
+		}
+		if len(cur) > 0 {
+			cur = append(cur, ' ')
+		}
+		cur = append(cur, w...)
+		for len(cur) > width {
+			lines = append(lines, string(cur[:width]))
+			cur = append(cur[:0], cur[width:]...)
+		}
+	}
+	if len(cur) > 0 || len(lines) == 0 {
+		lines = append(lines, string(cur))
+	}
+	return lines
+}
+
+// pdfWriter собирает объекты PDF и таблицу смещений (xref) для них
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// reserve выдает номер объекта заранее, чтобы на него можно было сослаться до записи
+func (p *pdfWriter) reserve() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) object(id int, body string) {
+	p.offsets[id-1] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (p *pdfWriter) stream(id int, dict string, data []byte) {
+	p.offsets[id-1] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	p.buf.Write(data)
+	p.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// writePDF кладет строки на страницы A4 и вшивает шрифт целиком как CIDFontType2:
+// текст записывается номерами глифов, а ToUnicode позволяет копировать его и искать по нему.
+func writePDF(w io.Writer, font *ttFont, title string, lines []string) error {
+	perPage := (pdfPageHeight - 2*pdfMarginY) / pdfLeading
+	charWidth := float64(font.width(font.glyph('0'))) * pdfFontSize / 1000
+	left := (pdfPageWidth - charWidth*pdfColumns) / 2
+
+	p := &pdfWriter{}
+	p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	catalog, pages, info := p.reserve(), p.reserve(), p.reserve()
+	fontID, cidFont, descriptor, fontFile, toUnicode := p.reserve(), p.reserve(), p.reserve(), p.reserve(), p.reserve()
+
+	used := make(map[uint16]rune)
+	var kids []string
+	for start := 0; start < len(lines) || start == 0; start += perPage {
+		end := min(start+perPage, len(lines))
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%.2f %d Td\n", pdfFontSize, pdfLeading, left, pdfPageHeight-pdfMarginY)
+		for _, line := range lines[start:end] {
+			content.WriteByte('<')
+			for _, r := range line {
+				gid := font.glyph(r)
+				if gid != 0 {
+					used[gid] = r
+				}
+				fmt.Fprintf(&content, "%04X", gid)
+			}
+			content.WriteString("> Tj T*\n")
+		}
+		content.WriteString("ET")
+
+		page, contents := p.reserve(), p.reserve()
+		p.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, pdfPageWidth, pdfPageHeight, fontID, contents))
+		data, err := deflate(content.Bytes())
+		if err != nil {
+			return err
+		}
+		p.stream(contents, "/Filter /FlateDecode", data)
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+
+	p.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	p.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	p.object(info, fmt.Sprintf("<< /Title %s /Producer (fooddelivery) >>", pdfText(title)))
+
+	const fontName = "/DejaVuSansMono"
+	p.object(fontID, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont %s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		fontName, cidFont, toUnicode))
+	p.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont %s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW %d /CIDToGIDMap /Identity >>",
+		fontName, descriptor, font.width(0)))
+	p.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName %s /Flags 33 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		fontName, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+		font.scale(font.ascent), font.scale(font.descent), font.scale(font.ascent), fontFile))
+	data, err := deflate(font.data)
+	if err != nil {
+		return err
+	}
+	p.stream(fontFile, fmt.Sprintf("/Filter /FlateDecode /Length1 %d", len(font.data)), data)
+	p.stream(toUnicode, "", toUnicodeCMap(used))
+
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, catalog, info, xref)
+
+	_, err = w.Write(p.buf.Bytes())
+	return err
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toUnicodeCMap сопоставляет глифы, попавшие в чек, с символами Unicode
+func toUnicodeCMap(used map[uint16]rune) []byte {
+	gids := make([]int, 0, len(used))
+	for gid := range used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// В одном блоке bfchar допускается не больше 100 записей
+	for len(gids) > 0 {
+		n := min(len(gids), 100)
+		fmt.Fprintf(&b, "%d beginbfchar\n", n)
+		for _, gid := range gids[:n] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", gid, utf16Hex(used[uint16(gid)]))
+		}
+		b.WriteString("endbfchar\n")
+		gids = gids[n:]
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return b.Bytes()
+}
+
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+// pdfText — строка для словаря PDF в UTF-16BE с BOM: так просмотрщики показывают кириллицу в заголовке
+func pdfText(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range s {
+		b.WriteString(utf16Hex(r))
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,179 @@
+// Package receipt — чек заказа: позиции со скидками, доставка и НДС по ставкам.
+// НДС считается по каждой строке отдельно (так его печатает касса), итоги по ставкам —
+// суммы строк. Чек собирается из снимка заказа, поэтому изменения каталога его не меняют,
+// и выдается только после списания денег: на ту сумму и на тот момент, когда прошел расчет.
+package receipt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+)
+
+// Seller — реквизиты продавца в шапке чека
+type Seller struct {
+	Name    string `json:"name"`
+	INN     string `json:"inn,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Line — строка чека
+type Line struct {
+	Name        string       `json:"name"` // с выбранными опциями
+	Quantity    int          `json:"quantity"`
+	Price       money.Money  `json:"price"`    // за штуку, с опциями
+	Amount      money.Money  `json:"amount"`   // Price * Quantity
+	Discount    money.Money  `json:"discount"` // скидка по промокоду на всю строку
+	Total       money.Money  `json:"total"`    // к оплате за строку
+	TaxCategory tax.Category `json:"tax_category"`
+	VATRate     int64        `json:"vat_rate"` // в процентах
+	VAT         money.Money  `json:"vat"`      // НДС внутри Total
+}
+
+// TaxTotal — итог по одной ставке НДС
+type TaxTotal struct {
+	TaxCategory tax.Category `json:"tax_category"`
+	VATRate     int64        `json:"vat_rate"`
+	Base        money.Money  `json:"base"` // сумма строк с этой ставкой, НДС внутри
+	VAT         money.Money  `json:"vat"`
+}
+
+type Receipt struct {
+	Number   string    `json:"number"`
+	OrderID  int64     `json:"order_id"`
+	IssuedAt time.Time `json:"issued_at"` // когда списаны деньги
+	Seller   Seller    `json:"seller"`
+
+	Lines       []Line      `json:"lines"` // еда и отдельной строкой доставка, если заказ доставлен
+	Subtotal    money.Money `json:"subtotal"`
+	Discount    money.Money `json:"discount"`
+	PromoCode   string      `json:"promo_code,omitempty"`
+	DeliveryFee money.Money `json:"delivery_fee"`
+	Total       money.Money `json:"total"`
+	Taxes       []TaxTotal  `json:"taxes"`
+	VATTotal    money.Money `json:"vat_total"`
+	// Paid — сколько списано с карты: Total и чаевые при оформлении, которые целиком уходят курьеру
+	Paid money.Money `json:"paid"`
+	Tip  money.Money `json:"tip"`
+}
+
+// deliveryName — как доставка называется в чеке
+const deliveryName = "Доставка"
+
+// Build собирает чек из заказа с позициями (repo.GetOrder) и итога списания по его платежу.
+// За недоставленный заказ (клиент отменил после начала готовки) списывается только еда,
+// поэтому строки доставки в таком чеке нет.
+func Build(o repo.Order, settled repo.Settled, seller Seller) Receipt {
+	r := Receipt{
+		Number:      fmt.Sprintf("%08d", o.ID),
+		OrderID:     o.ID,
+		IssuedAt:    settled.At,
+		Seller:      seller,
+		Subtotal:    money.Zero(),
+		Discount:    money.Zero(),
+		PromoCode:   o.PromoCode,
+		DeliveryFee: money.Zero(),
+		Total:       money.Zero(),
+		VATTotal:    money.Zero(),
+		Paid:        settled.Amount,
+		Tip:         money.Zero(),
+	}
+	delivered := o.Status == status.Completed
+
+	discounts := lineDiscounts(o)
+	for i, item := range o.Items {
+		amount := item.Price.Mul(item.Quantity)
+		r.Lines = append(r.Lines, newLine(lineName(item), item.Quantity, item.Price, discounts[i], item.TaxCategory))
+		r.Subtotal = r.Subtotal.Add(amount)
+		r.Discount = r.Discount.Add(discounts[i])
+	}
+	if delivered && o.Delivery != nil && o.Delivery.Fee.IsPositive() {
+		r.DeliveryFee = o.Delivery.Fee
+		r.Lines = append(r.Lines, newLine(deliveryName, 1, o.Delivery.Fee, money.Zero(), tax.Delivery))
+	}
+
+	byCategory := make(map[tax.Category]*TaxTotal)
+	for _, l := range r.Lines {
+		r.Total = r.Total.Add(l.Total)
+		r.VATTotal = r.VATTotal.Add(l.VAT)
+		t, ok := byCategory[l.TaxCategory]
+		if !ok {
+			t = &TaxTotal{TaxCategory: l.TaxCategory, VATRate: l.VATRate, Base: money.Zero(), VAT: money.Zero()}
+			byCategory[l.TaxCategory] = t
+		}
+		t.Base = t.Base.Add(l.Total)
+		t.VAT = t.VAT.Add(l.VAT)
+	}
+	if tip := r.Paid.Sub(r.Total); delivered && tip.IsPositive() {
+		r.Tip = tip
+	}
+	for _, t := range byCategory {
+		r.Taxes = append(r.Taxes, *t)
+	}
+	// Сначала большие ставки, как на кассовом чеке
+	sort.Slice(r.Taxes, func(i, j int) bool {
+		if r.Taxes[i].VATRate != r.Taxes[j].VATRate {
+			return r.Taxes[i].VATRate > r.Taxes[j].VATRate
+		}
+		return r.Taxes[i].TaxCategory < r.Taxes[j].TaxCategory
+	})
+	return r
+}
+
+func newLine(name string, quantity int, price, discount money.Money, category tax.Category) Line {
+	if category == "" {
+		category = tax.Default
+	}
+	amount := price.Mul(quantity)
+	total := amount.Sub(discount)
+	return Line{
+		Name:        name,
+		Quantity:    quantity,
+		Price:       price,
+		Amount:      amount,
+		Discount:    discount,
+		Total:       total,
+		TaxCategory: category,
+		VATRate:     category.Rate(),
+		VAT:         category.Included(total),
+	}
+}
+
+// lineDiscounts — скидки позиций. У заказов, оформленных до того, как скидка стала
+// записываться на позиции, она раскладывается по всем позициям пропорционально сумме.
+func lineDiscounts(o repo.Order) []money.Money {
+	discounts := make([]money.Money, len(o.Items))
+	sum := money.Zero()
+	lines := make([]promo.Line, len(o.Items))
+	for i, item := range o.Items {
+		discounts[i] = item.Discount
+		sum = sum.Add(discounts[i])
+		lines[i] = promo.Line{ProductID: item.ProductID, Amount: item.Price.Mul(item.Quantity)}
+	}
+	if o.Discount.IsPositive() && sum.IsZero() {
+		return promo.Code{}.Allocate(lines, o.Discount)
+	}
+	return discounts
+}
+
+func lineName(item repo.OrderItem) string {
+	name := item.Name
+	if name == "" {
+		name = fmt.Sprintf("Товар %d", item.ProductID)
+	}
+	if len(item.Options) == 0 {
+		return name
+	}
+	opts := make([]string, len(item.Options))
+	for i, opt := range item.Options {
+		opts[i] = opt.Name
+	}
+	return name + " (" + strings.Join(opts, ", ") + ")"
+}
@@ -0,0 +1,275 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+)
+
+func rub(minor int64) money.Money { return money.FromMinor(minor) }
+
+var settledAt = time.Date(2026, 10, 16, 19, 40, 0, 0, time.UTC)
+
+// order — борщ со скидкой (20%), молоко (10%) и доставка (20%)
+func order(st status.Status) repo.Order {
+	return repo.Order{
+		ID:         42,
+		Status:     st,
+		TotalPrice: rub(92900),
+		Discount:   rub(7000),
+		Tip:        rub(10000),
+		CreatedAt:  settledAt.Add(-2 * time.Hour),
+		Delivery:   &repo.Delivery{Fee: rub(19900)},
+		Items: []repo.OrderItem{
+			{ProductID: 1, Name: "Борщ", Quantity: 2, Price: rub(35000), Discount: rub(7000), TaxCategory: tax.VAT20,
+				Options: []repo.ItemOption{{Name: "Сметана"}, {Name: "Хлеб"}}},
+			{ProductID: 2, Quantity: 1, Price: rub(10000), TaxCategory: tax.VAT10},
+		},
+	}
+}
+
+func TestBuildDelivered(t *testing.T) {
+	r := Build(order(status.Completed), repo.Settled{Amount: rub(102900), At: settledAt}, Seller{Name: "ООО Ромашка"})
+
+	if r.Number != "00000042" || !r.IssuedAt.Equal(settledAt) {
+		t.Fatalf("шапка чека: %s, %v", r.Number, r.IssuedAt)
+	}
+	if len(r.Lines) != 3 {
+		t.Fatalf("ожидали две позиции и доставку, получили %+v", r.Lines)
+	}
+	borsch, milk, delivery := r.Lines[0], r.Lines[1], r.Lines[2]
+	if borsch.Name != "Борщ (Сметана, Хлеб)" || borsch.Amount != rub(70000) || borsch.Total != rub(63000) || borsch.VAT != rub(10500) {
+		t.Fatalf("строка борща: %+v", borsch)
+	}
+	// 100 ₽ с НДС 10%: 10/110 = 9.0909 -> 9.09
+	if milk.Name != "Товар 2" || milk.VATRate != 10 || milk.VAT != rub(909) {
+		t.Fatalf("строка молока: %+v", milk)
+	}
+	// 199 ₽ с НДС 20%: 199 * 20/120 = 33.1666 -> 33.17
+	if delivery.Name != deliveryName || delivery.TaxCategory != tax.Delivery || delivery.VAT != rub(3317) {
+		t.Fatalf("строка доставки: %+v", delivery)
+	}
+
+	if r.Subtotal != rub(80000) || r.Discount != rub(7000) || r.DeliveryFee != rub(19900) || r.Total != rub(92900) {
+		t.Fatalf("итоги: subtotal %v, discount %v, delivery %v, total %v", r.Subtotal, r.Discount, r.DeliveryFee, r.Total)
+	}
+	if len(r.Taxes) != 2 || r.Taxes[0].VATRate != 20 || r.Taxes[1].VATRate != 10 {
+		t.Fatalf("ставки должны идти от большей к меньшей: %+v", r.Taxes)
+	}
+	if r.Taxes[0].Base != rub(82900) || r.Taxes[0].VAT != rub(13817) || r.Taxes[1].VAT != rub(909) {
+		t.Fatalf("НДС по ставкам: %+v", r.Taxes)
+	}
+	// Итог по ставке — сумма НДС строк, а не НДС от суммы: 105 + 33.17, а не 829 * 20/120 = 138.1666
+	if r.VATTotal != rub(14726) {
+		t.Fatalf("VATTotal = %v", r.VATTotal)
+	}
+	// Сверх чека с карты списаны чаевые при оформлении
+	if r.Paid != rub(102900) || r.Tip != rub(10000) {
+		t.Fatalf("Paid = %v, Tip = %v", r.Paid, r.Tip)
+	}
+}
+
+func TestBuildCancelledAfterCooking(t *testing.T) {
+	// Клиент отменил заказ после начала готовки: списана только еда, без доставки и чаевых
+	r := Build(order(status.Cancelled), repo.Settled{Amount: rub(73000), At: settledAt}, Seller{})
+
+	if len(r.Lines) != 2 {
+		t.Fatalf("в чеке недоставленного заказа не должно быть доставки: %+v", r.Lines)
+	}
+	if !r.DeliveryFee.IsZero() || r.Total != rub(73000) || r.Paid != r.Total || !r.Tip.IsZero() {
+		t.Fatalf("итоги: delivery %v, total %v, paid %v, tip %v", r.DeliveryFee, r.Total, r.Paid, r.Tip)
+	}
+	if r.VATTotal != rub(10500+909) {
+		t.Fatalf("VATTotal = %v", r.VATTotal)
+	}
+}
+
+func TestBuildAllocatesLegacyDiscount(t *testing.T) {
+	// Заказ оформлен до того, как скидка стала записываться на позиции
+	o := repo.Order{
+		ID:       7,
+		Status:   status.Completed,
+		Discount: rub(100),
+		Items: []repo.OrderItem{
+			{ProductID: 1, Name: "Суп", Quantity: 1, Price: rub(20000), TaxCategory: tax.VAT20},
+			{ProductID: 2, Name: "Чай", Quantity: 1, Price: rub(10000), TaxCategory: tax.NoVAT},
+		},
+	}
+	r := Build(o, repo.Settled{Amount: rub(29900), At: settledAt}, Seller{})
+	if r.Lines[0].Discount != rub(67) || r.Lines[1].Discount != rub(33) || r.Discount != rub(100) {
+		t.Fatalf("скидка разложена неверно: %+v", r.Lines)
+	}
+	if r.Lines[1].VAT != rub(0) || r.Taxes[len(r.Taxes)-1].TaxCategory != tax.NoVAT {
+		t.Fatalf("товар без НДС: %+v, %+v", r.Lines[1], r.Taxes)
+	}
+	if r.Total != rub(29900) || !r.Tip.IsZero() {
+		t.Fatalf("total %v, tip %v", r.Total, r.Tip)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	r := Build(order(status.Cancelled), repo.Settled{Amount: rub(73000), At: settledAt}, Seller{Name: "ООО <Ромашка>"})
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{"Чек № 00000042", "ООО &lt;Ромашка&gt;", "730.00 ₽", "16.10.2026 19:40"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("в чеке нет %q", want)
+		}
+	}
+	if strings.Contains(page, "Доставка") {
+		t.Error("в чеке недоставленного заказа есть доставка")
+	}
+}
+
+func TestTextLines(t *testing.T) {
+	r := Build(order(status.Completed), repo.Settled{Amount: rub(102900), At: settledAt}, Seller{Name: "ООО Ромашка", INN: "7700000000"})
+	lines := r.textLines(40)
+	for _, l := range lines {
+		if n := len([]rune(l)); n > 40 {
+			t.Errorf("строка длиннее ленты (%d): %q", n, l)
+		}
+	}
+	text := strings.Join(lines, "\n")
+	if !strings.Contains(text, "ООО Ромашка, ИНН 7700000000") || !strings.Contains(text, "Борщ (Сметана, Хлеб)") {
+		t.Fatalf("нет шапки или названия позиции:\n%s", text)
+	}
+	// Суммы прижаты к правому краю ленты, подписи — к левому
+	for _, want := range [][2]string{
+		{"  2 x 350.00 ₽", "630.00 ₽"},
+		{"  скидка", "−70.00 ₽"},
+		{"ИТОГО", "929.00 ₽"},
+		{"Чаевые курьеру", "100.00 ₽"},
+		{"Списано с карты", "1029.00 ₽"},
+	} {
+		found := false
+		for _, l := range lines {
+			if strings.HasPrefix(l, want[0]+" ") && strings.HasSuffix(l, " "+want[1]) && len([]rune(l)) == 40 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("нет строки %q ... %q:\n%s", want[0], want[1], text)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{""}},
+		{"Борщ со сметаной", []string{"Борщ со", "сметаной"}},
+		{"Суперпуперкомбо", []string{"Суперпупе", "ркомбо"}},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.in, 9); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrap(%q) = %q, ожидали %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	r := Build(order(status.Completed), repo.Settled{Amount: rub(102900), At: settledAt}, Seller{Name: "ООО Ромашка"})
+	var buf bytes.Buffer
+	if err := r.WritePDF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.Bytes()
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatal("нет заголовка или конца PDF")
+	}
+
+	// Каждое смещение в xref должно указывать на начало своего объекта
+	start := bytes.LastIndex(doc, []byte("startxref\n"))
+	xref, err := strconv.Atoi(strings.Fields(string(doc[start+len("startxref\n"):]))[0])
+	if err != nil || !bytes.HasPrefix(doc[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref указывает мимо таблицы: %d, %v", xref, err)
+	}
+	entries := strings.Split(string(doc[xref:]), "\n")[3:]
+	for i, e := range entries {
+		if !strings.HasSuffix(e, " n ") {
+			break
+		}
+		off, _ := strconv.Atoi(e[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(doc[off:], []byte(want)) {
+			t.Fatalf("объект %d не найден по смещению %d", i+1, off)
+		}
+	}
+
+	// Текст страницы записан номерами глифов: переводим обратно через шрифт
+	font, err := monoFont()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runes := make(map[uint16]rune)
+	for r, gid := range font.cmap {
+		runes[gid] = r
+	}
+	text := pdfPageText(t, doc, runes)
+	for _, want := range []string{"Кассовый чек. Приход", "Чек № 00000042", "Борщ (Сметана, Хлеб)", "1029.00 ₽"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("в PDF нет %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestWritePDFPages(t *testing.T) {
+	o := order(status.Completed)
+	for i := 0; i < 40; i++ {
+		o.Items = append(o.Items, repo.OrderItem{ProductID: int64(i + 10), Quantity: 1, Price: rub(100), TaxCategory: tax.VAT20})
+	}
+	var buf bytes.Buffer
+	if err := Build(o, repo.Settled{Amount: rub(1), At: settledAt}, Seller{}).WritePDF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Count 3 ")) {
+		t.Fatal("длинный чек должен занять три страницы")
+	}
+}
+
+// pdfPageText распаковывает потоки страниц и собирает строки из <hex> Tj
+func pdfPageText(t *testing.T, doc []byte, runes map[uint16]rune) string {
+	t.Helper()
+	var text strings.Builder
+	for _, part := range bytes.Split(doc, []byte("/Filter /FlateDecode /Length "))[1:] {
+		body := part[bytes.Index(part, []byte("stream\n"))+len("stream\n"):]
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil || !bytes.HasPrefix(data, []byte("BT")) {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			hex, ok := strings.CutSuffix(line, "> Tj T*")
+			if !ok {
+				continue
+			}
+			hex = strings.TrimPrefix(hex, "<")
+			for i := 0; i+4 <= len(hex); i += 4 {
+				gid, err := strconv.ParseUint(hex[i:i+4], 16, 16)
+				if err != nil {
+					t.Fatalf("глиф %q: %v", hex[i:i+4], err)
+				}
+				text.WriteRune(runes[uint16(gid)])
+			}
+			text.WriteByte('\n')
+		}
+	}
+	return text.String()
+}
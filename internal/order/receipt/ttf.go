@@ -0,0 +1,164 @@
+package receipt
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Шрифт для PDF-чека вшит в бинарник: стандартные шрифты PDF не содержат кириллицы.
+// Моноширинный — чтобы колонки чека выравнивались пробелами, как на кассовой ленте.
+//
+//go:embed fonts/DejaVuSansMono.ttf
+var monoFontData []byte
+
+// ttFont — то, что PDF нужно знать о TrueType-шрифте: номера глифов для символов,
+// ширины глифов и метрики для описания шрифта
+type ttFont struct {
+	data       []byte
+	unitsPerEm int
+	bbox       [4]int // xMin, yMin, xMax, yMax
+	ascent     int
+	descent    int
+	advances   []uint16 // ширина глифа по его номеру; последняя повторяется для остальных глифов
+	cmap       map[rune]uint16
+}
+
+var errBadFont = errors.New("receipt: не удалось разобрать шрифт")
+
+// parseTrueType читает таблицы head, hhea, hmtx и cmap (формат 4, Unicode BMP)
+func parseTrueType(data []byte) (*ttFont, error) {
+	tables, err := ttTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"head", "hhea", "hmtx", "cmap"} {
+		if tables[name] == nil {
+			return nil, fmt.Errorf("%w: нет таблицы %s", errBadFont, name)
+		}
+	}
+	f := &ttFont{data: data, cmap: make(map[rune]uint16)}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, fmt.Errorf("%w: короткая таблица head", errBadFont)
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	if f.unitsPerEm == 0 {
+		return nil, fmt.Errorf("%w: unitsPerEm = 0", errBadFont)
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, fmt.Errorf("%w: короткая таблица hhea", errBadFont)
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	hmtx := tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, fmt.Errorf("%w: короткая таблица hmtx", errBadFont)
+	}
+	f.advances = make([]uint16, metrics)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+
+	if err := f.parseCmap(tables["cmap"]); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func ttTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*n {
+		return nil, errBadFont
+	}
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		rec := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: таблица %s за концом файла", errBadFont, rec[:4])
+		}
+		tables[string(rec[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// parseCmap разворачивает подтаблицу Windows Unicode BMP (3, 1) формата 4 в словарь символ -> глиф
+func (f *ttFont) parseCmap(cmap []byte) error {
+	if len(cmap) < 4 {
+		return errBadFont
+	}
+	var sub []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n && len(cmap) >= 4+8*(i+1); i++ {
+		rec := cmap[4+8*i:]
+		platform, encoding := binary.BigEndian.Uint16(rec), binary.BigEndian.Uint16(rec[2:])
+		offset := int(binary.BigEndian.Uint32(rec[4:]))
+		if (platform == 3 && encoding == 1 || platform == 0 && encoding == 3) && offset+2 <= len(cmap) &&
+			binary.BigEndian.Uint16(cmap[offset:]) == 4 {
+			sub = cmap[offset:]
+			break
+		}
+	}
+	if len(sub) < 14 {
+		return fmt.Errorf("%w: нет cmap формата 4", errBadFont)
+	}
+
+	segs := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	if len(sub) < 16+8*segs {
+		return fmt.Errorf("%w: короткая cmap", errBadFont)
+	}
+	ends, starts := sub[14:], sub[16+2*segs:]
+	deltas, rangeOffsets := sub[16+4*segs:], 16+6*segs
+	u16 := func(b []byte, i int) uint16 { return binary.BigEndian.Uint16(b[2*i:]) }
+	for s := 0; s < segs; s++ {
+		start, end := u16(starts, s), u16(ends, s)
+		delta := u16(deltas, s)
+		ro := int(binary.BigEndian.Uint16(sub[rangeOffsets+2*s:]))
+		for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+			var gid uint16
+			if ro == 0 {
+				gid = uint16(c) + delta
+			} else {
+				at := rangeOffsets + 2*s + ro + 2*int(c-uint32(start))
+				if at+2 > len(sub) {
+					continue
+				}
+				if gid = binary.BigEndian.Uint16(sub[at:]); gid != 0 {
+					gid += delta
+				}
+			}
+			if gid != 0 {
+				f.cmap[rune(c)] = gid
+			}
+		}
+	}
+	return nil
+}
+
+// glyph — номер глифа для символа; 0 — в шрифте такого символа нет
+func (f *ttFont) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// width — ширина глифа в тысячных долях кегля, как их ждет PDF
+func (f *ttFont) width(gid uint16) int {
+	i := min(int(gid), len(f.advances)-1)
+	return f.scale(int(f.advances[i]))
+}
+
+func (f *ttFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
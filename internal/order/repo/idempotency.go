@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrIdempotencyMismatch — ключ уже использован с другим телом запроса
+var ErrIdempotencyMismatch = errors.New("Idempotency-Key уже использован с другим запросом")
+
+// Idempotency — ключ из заголовка Idempotency-Key и хэш тела запроса. Ключи живут TTL,
+// после этого тот же ключ создаст новый заказ.
+type Idempotency struct {
+	Key  string
+	Hash string
+	TTL  time.Duration
+}
+
+func (r *pgRepo) FindIdempotentOrder(ctx context.Context, userID int64, idem Idempotency) (int64, error) {
+	var hash string
+	var orderID *int64
+	query := `
+		SELECT request_hash, order_id FROM order_idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND created_at >= NOW() - make_interval(secs => $3)`
+	err := r.db.QueryRow(ctx, query, userID, idem.Key, idem.TTL.Seconds()).Scan(&hash, &orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if hash != idem.Hash {
+		return 0, ErrIdempotencyMismatch
+	}
+	// order_id пуст, пока первая попытка еще создает заказ: дождемся ее в CreateOrder
+	if orderID == nil {
+		return 0, nil
+	}
+	return *orderID, nil
+}
+
+func (r *pgRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := "DELETE FROM order_idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)"
+	result, err := r.db.Exec(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// claimIdempotencyKey занимает ключ в транзакции создания заказа. Параллельный запрос
+// с тем же ключом ждет на уникальном индексе, пока эта транзакция не завершится:
+// после коммита он получит готовый order_id, после отката займет ключ сам.
+// Возвращает id уже созданного заказа или 0, если ключ занят этой транзакцией.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, userID int64, idem Idempotency) (int64, error) {
+	query := `
+		DELETE FROM order_idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND created_at < NOW() - make_interval(secs => $3)`
+	if _, err := tx.Exec(ctx, query, userID, idem.Key, idem.TTL.Seconds()); err != nil {
+		return 0, err
+	}
+	query = `
+		INSERT INTO order_idempotency_keys (user_id, key, request_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, key) DO NOTHING`
+	result, err := tx.Exec(ctx, query, userID, idem.Key, idem.Hash)
+	if err != nil {
+		return 0, err
+	}
+	if result.RowsAffected() == 1 {
+		return 0, nil
+	}
+
+	var hash string
+	var orderID *int64
+	query = "SELECT request_hash, order_id FROM order_idempotency_keys WHERE user_id = $1 AND key = $2"
+	if err := tx.QueryRow(ctx, query, userID, idem.Key).Scan(&hash, &orderID); err != nil {
+		return 0, err
+	}
+	if hash != idem.Hash {
+		return 0, ErrIdempotencyMismatch
+	}
+	if orderID == nil {
+		// Ключ без заказа после чужого коммита не остается: order_id пишется в той же транзакции
+		return 0, fmt.Errorf("ключ идемпотентности %q без заказа", idem.Key)
+	}
+	return *orderID, nil
+}
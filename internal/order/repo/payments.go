@@ -0,0 +1,280 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotPayable — заказ уже оплачен, отменен или еще не ждет оплаты
+var ErrNotPayable = errors.New("заказ не ждет оплаты")
+
+// ReasonPaid — причина перехода заказа после успешной оплаты
+const ReasonPaid = "оплата подтверждена"
+
+// Способы завершить заблокированный платеж
+const (
+	SettleCapture = "capture"
+	SettleRefund  = "refund"
+)
+
+type Payment struct {
+	ID         int64          `json:"id"`
+	OrderID    int64          `json:"order_id"`
+	Provider   string         `json:"provider"`
+	ProviderID string         `json:"-"`
+	Amount     money.Money    `json:"amount"`
+	Status     payment.Status `json:"status"`
+	Attempt    int            `json:"attempt"`
+	LastError  string         `json:"last_error,omitempty"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	// Settled — сколько и когда списано или возвращено; nil, пока блокировка не закрыта
+	Settled *Settled `json:"settled,omitempty"`
+}
+
+// Settled — итог расчета по платежу
+type Settled struct {
+	Amount money.Money `json:"amount"`
+	At     time.Time   `json:"at"`
+}
+
+// PaymentUpdate — что узнали о платеже от провайдера. Пустой ProviderID не меняет сохраненный.
+type PaymentUpdate struct {
+	Status     payment.Status
+	ProviderID string
+	Error      string
+	// Amount — сколько списано или возвращено при переходе в captured/refunded.
+	// Ноль — сумма неизвестна (вебхук), считаем, что расчет прошел на всю блокировку
+	Amount money.Money
+}
+
+// Settlement — заблокированный платеж, который пора списать или вернуть
+type Settlement struct {
+	Payment Payment
+	Action  string // SettleCapture или SettleRefund
+	// Amount — сколько списать или вернуть. За недоставленный заказ не списываются ни чаевые, ни доставка
+	Amount money.Money
+}
+
+const paymentColumns = `
+	SELECT id, order_id, provider, COALESCE(provider_payment_id, ''), amount, status, attempt,
+		COALESCE(last_error, ''), updated_at, settled_amount, settled_at
+	FROM payments`
+
+func scanPayment(row pgx.Row) (Payment, error) {
+	var p Payment
+	var settledAmount *money.Money
+	var settledAt *time.Time
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.ProviderID, &p.Amount, &p.Status, &p.Attempt, &p.LastError, &p.UpdatedAt,
+		&settledAmount, &settledAt)
+	if err == nil && settledAmount != nil && settledAt != nil {
+		p.Settled = &Settled{Amount: *settledAmount, At: *settledAt}
+	}
+	return p, err
+}
+
+func (r *pgRepo) StartPayment(ctx context.Context, userID, orderID int64, provider string) (Payment, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return Payment{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка заказа не дает двум запросам оплаты завести два платежа.
+	// Чаевые при оформлении блокируются вместе с заказом
+	var owner int64
+	var st status.Status
+	var amount money.Money
+	query := `
+		SELECT o.user_id, o.status, o.total_price + COALESCE(t.amount, 0)
+		FROM orders o
+		LEFT JOIN tips t ON t.order_id = o.id AND t.source = 'checkout' AND t.status = 'pending'
+		WHERE o.id = $1 FOR UPDATE OF o`
+	err = tx.QueryRow(ctx, query, orderID).Scan(&owner, &st, &amount)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && owner != userID) {
+		return Payment{}, false, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return Payment{}, false, err
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, paymentColumns+" WHERE order_id = $1", orderID))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if st != status.AwaitingPayment {
+			return Payment{}, false, fmt.Errorf("%w: заказ %d в статусе %s", ErrNotPayable, orderID, st)
+		}
+		query := `
+			INSERT INTO payments (order_id, provider, amount) VALUES ($1, $2, $3)
+			RETURNING id, attempt, updated_at`
+		p = Payment{OrderID: orderID, Provider: provider, Amount: amount, Status: payment.Pending}
+		if err := tx.QueryRow(ctx, query, orderID, provider, amount).Scan(&p.ID, &p.Attempt, &p.UpdatedAt); err != nil {
+			return Payment{}, false, err
+		}
+	case err != nil:
+		return Payment{}, false, err
+	case payment.CanTransition(p.Status, payment.Pending) && st == status.AwaitingPayment:
+		// Банк отказал или провайдер не ответил — пробуем заново, возможно с другой картой
+		query := `
+			UPDATE payments SET status = 'pending', provider = $2, provider_payment_id = NULL, amount = $3,
+				attempt = attempt + 1, last_error = NULL, updated_at = NOW()
+			WHERE id = $1 RETURNING attempt, updated_at`
+		if err := tx.QueryRow(ctx, query, p.ID, provider, amount).Scan(&p.Attempt, &p.UpdatedAt); err != nil {
+			return Payment{}, false, err
+		}
+		p.Provider, p.ProviderID, p.Amount, p.Status, p.LastError = provider, "", amount, payment.Pending, ""
+	default:
+		return p, false, tx.Commit(ctx)
+	}
+	return p, true, tx.Commit(ctx)
+}
+
+func (r *pgRepo) GetPayment(ctx context.Context, userID, orderID int64) (Payment, error) {
+	query := paymentColumns + " WHERE order_id = (SELECT id FROM orders WHERE id = $1 AND user_id = $2)"
+	p, err := scanPayment(r.db.QueryRow(ctx, query, orderID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, fmt.Errorf("%w: заказ %d", payment.ErrNotFound, orderID)
+	}
+	return p, err
+}
+
+func (r *pgRepo) PaymentByProviderID(ctx context.Context, provider, providerID string) (Payment, error) {
+	query := paymentColumns + " WHERE provider = $1 AND provider_payment_id = $2"
+	p, err := scanPayment(r.db.QueryRow(ctx, query, provider, providerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, fmt.Errorf("%w: %s %s", payment.ErrNotFound, provider, providerID)
+	}
+	return p, err
+}
+
+func (r *pgRepo) UpdatePayment(ctx context.Context, id int64, u PaymentUpdate) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var from payment.Status
+	var orderID int64
+	err = tx.QueryRow(ctx, "SELECT status, order_id FROM payments WHERE id = $1 FOR UPDATE", id).Scan(&from, &orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %d", payment.ErrNotFound, id)
+	}
+	if err != nil {
+		return err
+	}
+	// Повторный вебхук о том же — не ошибка. Статус не меняется и у pending-ответа на Authorize
+	// (запоминаем id у провайдера) и у неудачной попытки списания (запоминаем ошибку)
+	if from == u.Status {
+		if u.ProviderID == "" && u.Error == "" {
+			return nil
+		}
+		query := `
+			UPDATE payments SET provider_payment_id = COALESCE(NULLIF($2, ''), provider_payment_id),
+				last_error = COALESCE(NULLIF($3, ''), last_error), updated_at = NOW()
+			WHERE id = $1`
+		if _, err := tx.Exec(ctx, query, id, u.ProviderID, u.Error); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	if !payment.CanTransition(from, u.Status) {
+		return fmt.Errorf("%w: %s -> %s", payment.ErrIllegalTransition, from, u.Status)
+	}
+	query := `
+		UPDATE payments SET status = $2, provider_payment_id = COALESCE(NULLIF($3, ''), provider_payment_id),
+			last_error = NULLIF($4, ''), updated_at = NOW()
+		WHERE id = $1`
+	if _, err := tx.Exec(ctx, query, id, u.Status, u.ProviderID, u.Error); err != nil {
+		return err
+	}
+	switch u.Status {
+	case payment.Authorized:
+		if err := releasePaid(ctx, tx, orderID); err != nil {
+			return err
+		}
+	case payment.Captured, payment.Refunded:
+		var amount any // NULL — берем всю блокировку
+		if u.Amount.IsPositive() {
+			amount = u.Amount
+		}
+		query := "UPDATE payments SET settled_amount = COALESCE($2, amount), settled_at = NOW() WHERE id = $1"
+		if _, err := tx.Exec(ctx, query, id, amount); err != nil {
+			return err
+		}
+		if err := settleCheckoutTip(ctx, tx, orderID, u.Status); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// releasePaid отдает оплаченный заказ кухне, а заказ ко времени — планировщику.
+// Заказ, который успели отменить, не трогаем: блокировку снимет PaymentsToSettle.
+func releasePaid(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	var st status.Status
+	var scheduled bool
+	query := "SELECT status, deliver_at IS NOT NULL FROM orders WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRow(ctx, query, orderID).Scan(&st, &scheduled); err != nil {
+		return err
+	}
+	if st != status.AwaitingPayment {
+		return nil
+	}
+	to := status.New
+	if scheduled {
+		to = status.Scheduled
+	}
+	if _, err := status.Apply(ctx, tx, orderID, to, status.Actor{Type: status.ActorSystem}, ReasonPaid); err != nil {
+		return err
+	}
+	// Запоминаем, когда заказ ушел на кухню
+	if to == status.New {
+		if _, err := tx.Exec(ctx, "UPDATE orders SET released_at = NOW() WHERE id = $1", orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleOnCancel решает, что сделать с деньгами отмененного заказа: клиент, отменивший
+// заказ после начала готовки, за еду платит; в остальных случаях блокировка снимается
+func settleOnCancel(ctx context.Context, tx pgx.Tx, orderID int64, actor status.Actor, free bool) error {
+	action := SettleRefund
+	if !free && actor.Type == status.ActorClient {
+		action = SettleCapture
+	}
+	_, err := tx.Exec(ctx, "UPDATE payments SET settle_action = $2 WHERE order_id = $1", orderID, action)
+	return err
+}
+
+func (r *pgRepo) PaymentsToSettle(ctx context.Context, limit int) ([]Settlement, error) {
+	query := `
+		SELECT p.id, p.order_id, p.provider, COALESCE(p.provider_payment_id, ''), p.amount, p.status, p.attempt,
+			COALESCE(p.last_error, ''), p.updated_at,
+			CASE WHEN o.status = 'completed' OR p.settle_action = 'capture' THEN 'capture' ELSE 'refund' END,
+			CASE WHEN o.status <> 'completed' AND p.settle_action = 'capture'
+				THEN p.amount - COALESCE(t.amount, 0) - o.delivery_fee ELSE p.amount END
+		FROM payments p
+		JOIN orders o ON o.id = p.order_id
+		LEFT JOIN tips t ON t.order_id = p.order_id AND t.source = 'checkout'
+		WHERE p.status = 'authorized' AND o.status IN ('completed', 'cancelled', 'failed')
+		ORDER BY p.id LIMIT $1`
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Settlement, error) {
+		var s Settlement
+		p := &s.Payment
+		err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.ProviderID, &p.Amount, &p.Status, &p.Attempt,
+			&p.LastError, &p.UpdatedAt, &s.Action, &s.Amount)
+		return s, err
+	})
+}
@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const promoColumns = `
+	SELECT id, code, kind, percent, amount, min_basket, first_order_only, max_uses, per_user_limit,
+		used_count, valid_from, valid_until, product_ids, category_ids, is_active
+	FROM promo_codes`
+
+func scanPromoCode(row pgx.Row) (promo.Code, error) {
+	var c promo.Code
+	err := row.Scan(&c.ID, &c.Code, &c.Kind, &c.Percent, &c.Amount, &c.MinBasket, &c.FirstOrderOnly, &c.MaxUses, &c.PerUserLimit,
+		&c.UsedCount, &c.ValidFrom, &c.ValidUntil, &c.ProductIDs, &c.CategoryIDs, &c.IsActive)
+	return c, err
+}
+
+func (r *pgRepo) GetPromoCode(ctx context.Context, code string) (promo.Code, error) {
+	c, err := scanPromoCode(r.db.QueryRow(ctx, promoColumns+" WHERE code = $1", promo.Normalize(code)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return promo.Code{}, promo.ErrNotFound
+	}
+	return c, err
+}
+
+func (r *pgRepo) CreatePromoCode(ctx context.Context, c promo.Code) (int64, error) {
+	query := `
+		INSERT INTO promo_codes (code, kind, percent, amount, min_basket, first_order_only, max_uses, per_user_limit,
+			valid_from, valid_until, product_ids, category_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+	productIDs, categoryIDs := c.ProductIDs, c.CategoryIDs
+	if productIDs == nil {
+		productIDs = []int64{}
+	}
+	if categoryIDs == nil {
+		categoryIDs = []int64{}
+	}
+	var id int64
+	err := r.db.QueryRow(ctx, query, c.Code, c.Kind, c.Percent, c.Amount, c.MinBasket, c.FirstOrderOnly, c.MaxUses, c.PerUserLimit,
+		c.ValidFrom, c.ValidUntil, productIDs, categoryIDs).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return 0, fmt.Errorf("%w: %s", promo.ErrDuplicate, c.Code)
+	}
+	return id, err
+}
+
+func (r *pgRepo) ListPromoCodes(ctx context.Context) ([]promo.Code, error) {
+	rows, err := r.db.Query(ctx, promoColumns+" ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (promo.Code, error) {
+		return scanPromoCode(row)
+	})
+}
+
+func (r *pgRepo) DeactivatePromoCode(ctx context.Context, code string) error {
+	result, err := r.db.Exec(ctx, "UPDATE promo_codes SET is_active = FALSE WHERE code = $1", promo.Normalize(code))
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return promo.ErrNotFound
+	}
+	return nil
+}
+
+// usePromoCode засчитывает использование кода заказом orderID. UPDATE с проверкой лимита
+// блокирует строку кода, поэтому параллельные заказы с тем же кодом считаются по очереди
+// и не превысят ни общий лимит, ни лимит пользователя.
+func usePromoCode(ctx context.Context, tx pgx.Tx, c promo.Code, userID, orderID int64) error {
+	query := `
+		UPDATE promo_codes SET used_count = used_count + 1
+		WHERE id = $1 AND is_active
+			AND (valid_from IS NULL OR valid_from <= NOW()) AND (valid_until IS NULL OR valid_until > NOW())
+			AND (max_uses IS NULL OR used_count < max_uses)
+		RETURNING per_user_limit, first_order_only`
+	var perUser *int
+	var firstOnly bool
+	err := tx.QueryRow(ctx, query, c.ID).Scan(&perUser, &firstOnly)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Код выключили, он истек или его разобрали, пока считался заказ
+		var active bool
+		if err := tx.QueryRow(ctx, "SELECT is_active AND (valid_until IS NULL OR valid_until > NOW()) FROM promo_codes WHERE id = $1", c.ID).Scan(&active); err != nil {
+			return err
+		}
+		if !active {
+			return promo.ErrInactive
+		}
+		return promo.ErrExhausted
+	}
+	if err != nil {
+		return err
+	}
+
+	if perUser != nil {
+		var used int
+		query := "SELECT COUNT(*) FROM promo_code_usages WHERE promo_code_id = $1 AND user_id = $2"
+		if err := tx.QueryRow(ctx, query, c.ID, userID).Scan(&used); err != nil {
+			return err
+		}
+		if used >= *perUser {
+			return promo.ErrUserLimit
+		}
+	}
+	if firstOnly {
+		// Отмененные заказы не считаются: клиент еще ничего у нас не получил
+		var hasOrders bool
+		query := "SELECT EXISTS (SELECT 1 FROM orders WHERE user_id = $1 AND id <> $2 AND status <> 'cancelled')"
+		if err := tx.QueryRow(ctx, query, userID, orderID).Scan(&hasOrders); err != nil {
+			return err
+		}
+		if hasOrders {
+			return promo.ErrFirstOrder
+		}
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO promo_code_usages (promo_code_id, user_id, order_id) VALUES ($1, $2, $3)", c.ID, userID, orderID)
+	return err
+}
+
+// releasePromoCode возвращает использование кода при отмене заказа
+func releasePromoCode(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	var promoID int64
+	err := tx.QueryRow(ctx, "DELETE FROM promo_code_usages WHERE order_id = $1 RETURNING promo_code_id", orderID).Scan(&promoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE promo_codes SET used_count = used_count - 1 WHERE id = $1 AND used_count > 0", promoID)
+	return err
+}
@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/jackc/pgx/v5"
+)
+
+const orderColumns = `
+	SELECT o.id, o.user_id, o.status, o.total_price, o.discount, COALESCE(t.amount, 0), COALESCE(p.code, ''), o.created_at, c.id, c.name,
+		o.delivery_lat IS NOT NULL, COALESCE(o.delivery_address, ''), COALESCE(o.delivery_lat, 0), COALESCE(o.delivery_lon, 0),
+		COALESCE(o.delivery_distance_km, 0), o.delivery_fee, o.deliver_at, o.release_at
+	FROM orders o
+	LEFT JOIN couriers c ON c.id = o.courier_id
+	LEFT JOIN promo_codes p ON p.id = o.promo_code_id
+	LEFT JOIN tips t ON t.order_id = o.id AND t.status IN ('pending', 'paid')`
+
+func scanOrder(row pgx.Row) (Order, error) {
+	var o Order
+	var courierID *int64
+	var courierName *string
+	var hasDelivery bool
+	var d Delivery
+	var deliverAt, releaseAt *time.Time
+	err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.TotalPrice, &o.Discount, &o.Tip, &o.PromoCode, &o.CreatedAt, &courierID, &courierName,
+		&hasDelivery, &d.Address, &d.Lat, &d.Lon, &d.DistanceKm, &d.Fee, &deliverAt, &releaseAt)
+	if err != nil {
+		return Order{}, err
+	}
+	if deliverAt != nil && releaseAt != nil {
+		o.Slot = &Slot{DeliverAt: *deliverAt, ReleaseAt: *releaseAt}
+	}
+	if hasDelivery {
+		o.Delivery = &d
+	}
+	if courierID != nil {
+		o.Courier = &Courier{ID: *courierID}
+		if courierName != nil {
+			o.Courier.Name = *courierName
+		}
+	}
+	return o, nil
+}
+
+func (r *pgRepo) GetOrder(ctx context.Context, orderID int64) (Order, error) {
+	o, err := scanOrder(r.db.QueryRow(ctx, orderColumns+" WHERE o.id = $1", orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Order{}, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return Order{}, err
+	}
+
+	items, err := r.loadItems(ctx, []int64{orderID})
+	if err != nil {
+		return Order{}, err
+	}
+	o.Items = items[orderID]
+
+	if o.History, err = status.History(ctx, r.db, orderID); err != nil {
+		return Order{}, err
+	}
+	return o, nil
+}
+
+func (r *pgRepo) ListOrders(ctx context.Context, f ListFilter) ([]Order, error) {
+	statuses := make([]string, len(f.Statuses))
+	for i, st := range f.Statuses {
+		statuses[i] = string(st)
+	}
+	query := orderColumns + `
+		WHERE o.user_id = $1
+			AND (cardinality($2::text[]) = 0 OR o.status = ANY($2))
+			AND ($3 = 0 OR o.id < $3)
+		ORDER BY o.id DESC
+		LIMIT $4`
+	rows, err := r.db.Query(ctx, query, f.UserID, statuses, f.Before, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Order, error) {
+		return scanOrder(row)
+	})
+	if err != nil || len(orders) == 0 {
+		return orders, err
+	}
+
+	ids := make([]int64, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := r.loadItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].ID]
+	}
+	return orders, nil
+}
+
+// loadItems читает позиции нескольких заказов сразу вместе с выбранными опциями.
+// Цена — та, по которой заказали; название берем из каталога, даже если товар потом удалили.
+func (r *pgRepo) loadItems(ctx context.Context, orderIDs []int64) (map[int64][]OrderItem, error) {
+	query := `
+		SELECT i.id, i.order_id, i.product_id, COALESCE(p.name, ''), i.quantity, i.price_at_purchase, i.discount, i.tax_category
+		FROM order_items i
+		LEFT JOIN products p ON p.id = i.product_id
+		WHERE i.order_id = ANY($1)
+		ORDER BY i.id`
+	rows, err := r.db.Query(ctx, query, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type ref struct {
+		orderID int64
+		index   int
+	}
+	result := make(map[int64][]OrderItem, len(orderIDs))
+	refs := make(map[int64]ref)
+	var itemIDs []int64
+	for rows.Next() {
+		var itemID, orderID int64
+		var item OrderItem
+		if err := rows.Scan(&itemID, &orderID, &item.ProductID, &item.Name, &item.Quantity, &item.Price, &item.Discount, &item.TaxCategory); err != nil {
+			return nil, err
+		}
+		refs[itemID] = ref{orderID: orderID, index: len(result[orderID])}
+		result[orderID] = append(result[orderID], item)
+		itemIDs = append(itemIDs, itemID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT order_item_id, option_id, name, price_delta
+		FROM order_item_options WHERE order_item_id = ANY($1) ORDER BY id`, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	var itemID int64
+	var opt ItemOption
+	_, err = pgx.ForEachRow(rows, []any{&itemID, &opt.OptionID, &opt.Name, &opt.PriceDelta}, func() error {
+		ref := refs[itemID]
+		item := &result[ref.orderID][ref.index]
+		item.Options = append(item.Options, opt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
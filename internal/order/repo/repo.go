@@ -2,25 +2,191 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/JuniorCrafter/fooddelivery/internal/geo"
+	orderevents "github.com/JuniorCrafter/fooddelivery/internal/order/events"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/review"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/JuniorCrafter/fooddelivery/internal/tax"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrProductNotFound    = errors.New("товар не найден")
+	ErrProductUnavailable = errors.New("товар недоступен для заказа")
+	ErrOutOfStock         = errors.New("товар закончился")
+	ErrPriceChanged       = errors.New("цена товара изменилась, обновите корзину")
+)
+
+// ReasonExpired — причина автоматической отмены неоплаченного заказа
+const ReasonExpired = "заказ не оплачен за время резерва"
+
+// Cancellation — итог отмены заказа; в том же виде уходит в событие order.cancelled
+type Cancellation = orderevents.OrderCancelledData
+
 type OrderItem struct {
-	ProductID int64   `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID int64        `json:"product_id"`
+	Name      string       `json:"name,omitempty"` // только при чтении заказа: название из каталога
+	Quantity  int          `json:"quantity"`
+	Price     money.Money  `json:"price"` // цена за единицу вместе с опциями; присланную клиентом сервер перезаписывает
+	Options   []ItemOption `json:"options,omitempty"`
+	// Discount — часть скидки по промокоду, пришедшаяся на позицию; TaxCategory — ставка НДС на момент заказа.
+	// Обе считает сервер, присланные клиентом перезаписываются.
+	Discount    money.Money  `json:"discount"`
+	TaxCategory tax.Category `json:"tax_category,omitempty"`
+
+	BasePrice money.Money `json:"-"` // цена товара из каталога без опций, по ней заказ сверяется в транзакции
+}
+
+// ItemOption — выбранная клиентом опция. Клиент присылает только option_id,
+// название и надбавку сервер подставляет сам из каталога.
+type ItemOption struct {
+	OptionID   int64       `json:"option_id"`
+	Name       string      `json:"name"`
+	PriceDelta money.Money `json:"price_delta"`
+}
+
+// CatalogProduct — то, что заказу нужно знать о товаре из каталога: актуальная цена,
+// доступность и когда его можно заказать (расписание кухни и окно подачи)
+type CatalogProduct struct {
+	Name            string
+	Price           money.Money
+	TaxCategory     tax.Category
+	IsAvailable     bool  // не в стоп-листе
+	KitchenID       int64 // 0 — товар не привязан к кухне
+	Kitchen         schedule.Schedule
+	AvailableFrom   string
+	AvailableUntil  string
+	LimitedStock    bool       // у товара ведется остаток, заказ изменит его
+	CategoryID      int64      // 0 — без категории; нужна промокодам с ограничением по категориям
+	KitchenLocation *geo.Point // nil — у кухни не заданы координаты
+}
+
+// OptionGroup — правила выбора опций товара, как они заведены в каталоге
+type OptionGroup struct {
+	ID        int64
+	Name      string
+	MinSelect int
+	MaxSelect int
+	Options   []ItemOption
 }
 
 type Order struct {
-	ID         int64       `json:"id"`
-	UserID     int64       `json:"user_id"`
-	TotalPrice float64     `json:"total_price"`
-	Items      []OrderItem `json:"items"`
+	ID         int64         `json:"id"`
+	UserID     int64         `json:"user_id"`
+	Status     status.Status `json:"status,omitempty"`
+	TotalPrice money.Money   `json:"total_price"` // к оплате, уже со скидкой
+	Discount   money.Money   `json:"discount"`
+	Tip        money.Money   `json:"tip"` // чаевые курьеру сверх TotalPrice; отмененные и не прошедшие оплату не считаются
+	PromoCode  string        `json:"promo_code,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Delivery   *Delivery     `json:"delivery,omitempty"` // nil у заказов, оформленных до появления доставки
+	Slot       *Slot         `json:"slot,omitempty"`     // nil — доставить как можно скорее
+	Courier    *Courier      `json:"courier,omitempty"`  // nil, пока заказ никто не взял
+	Items      []OrderItem   `json:"items"`
+
+	History []status.Change `json:"history,omitempty"` // заполняется только в GetOrder
+
+	Promo *promo.Code `json:"-"` // при создании: промокод, уже проверенный по правилам; лимиты проверит CreateOrder
+}
+
+// Delivery — куда везти заказ и сколько стоит доставка. Клиент присылает адрес и координаты,
+// расстояние и цену считает сервер.
+type Delivery struct {
+	Address    string      `json:"address"`
+	Lat        float64     `json:"lat"`
+	Lon        float64     `json:"lon"`
+	DistanceKm float64     `json:"distance_km"`
+	Fee        money.Money `json:"fee"`
+}
+
+// Courier — кто везет заказ
+type Courier struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// ListFilter — выборка заказов пользователя. Листаем от новых к старым по id:
+// Before — курсор, id последнего заказа предыдущей страницы (0 — с начала)
+type ListFilter struct {
+	UserID   int64
+	Statuses []status.Status // пусто — любые
+	Before   int64
+	Limit    int
 }
 
 type Repository interface {
-	CreateOrder(ctx context.Context, o Order) (int64, error)
+	// CreateOrder сохраняет заказ. С idem повтор того же запроса вернет уже созданный заказ
+	// и replayed = true; тот же ключ с другим запросом — ErrIdempotencyMismatch
+	CreateOrder(ctx context.Context, o Order, idem *Idempotency) (id int64, replayed bool, err error)
+	// FindIdempotentOrder — заказ, уже созданный по ключу (0 — еще нет)
+	FindIdempotentOrder(ctx context.Context, userID int64, idem Idempotency) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, olderThan time.Duration) (int64, error)
+	// GetPromoCode ищет код без учета регистра; нет такого — promo.ErrNotFound
+	GetPromoCode(ctx context.Context, code string) (promo.Code, error)
+	CreatePromoCode(ctx context.Context, c promo.Code) (int64, error)
+	ListPromoCodes(ctx context.Context) ([]promo.Code, error)
+	DeactivatePromoCode(ctx context.Context, code string) error
+	GetOptionGroups(ctx context.Context, productID int64) ([]OptionGroup, error)
+	// GetCatalogProducts читает товары из общей базы каталога; удаленных товаров в ответе нет
+	GetCatalogProducts(ctx context.Context, productIDs []int64) (map[int64]CatalogProduct, error)
+	// ChangeStatus переводит заказ по машине состояний; перевод в cancelled работает как CancelOrder.
+	// released — сколько резервов снято
+	ChangeStatus(ctx context.Context, orderID int64, to status.Status, actor status.Actor, reason string) (released int64, err error)
+	// CancelOrder отменяет заказ по правилам status.FreeCancel и освобождает курьера.
+	// Клиент (actor client) может отменить только свой заказ
+	CancelOrder(ctx context.Context, orderID int64, actor status.Actor, reason string) (c Cancellation, released int64, err error)
+	GetStatusHistory(ctx context.Context, orderID int64) ([]status.Change, error)
+	// OrderParties — владелец заказа и пользователь назначенного курьера (0 — курьера нет)
+	OrderParties(ctx context.Context, orderID int64) (userID, courierUserID int64, err error)
+	// GetOrder — заказ с позициями, курьером и историей статусов
+	GetOrder(ctx context.Context, orderID int64) (Order, error)
+	// ListOrders — заказы пользователя с позициями, от новых к старым
+	ListOrders(ctx context.Context, f ListFilter) ([]Order, error)
+	// ReleaseScheduled переводит в new заказы ко времени, которым пора на кухню
+	ReleaseScheduled(ctx context.Context) (int64, error)
+	// ExpireOrders отменяет заказы, которые дольше olderThan ждут оплаты, и снимает их резерв.
+	// Оплаченные заказы по таймауту не отменяются: клиент уже заплатил, и пока курьер не взял
+	// заказ, решение за рестораном или поддержкой
+	ExpireOrders(ctx context.Context, olderThan time.Duration) (cancelled, released int64, err error)
+
+	// StartPayment заводит платеж за заказ владельца, ожидающий оплаты; created = false —
+	// платеж уже идет или прошел, и его возвращаем как есть
+	StartPayment(ctx context.Context, userID, orderID int64, provider string) (p Payment, created bool, err error)
+	GetPayment(ctx context.Context, userID, orderID int64) (Payment, error)
+	// UpdatePayment переводит платеж в новый статус; authorized отдает заказ на кухню (или в scheduled)
+	UpdatePayment(ctx context.Context, id int64, u PaymentUpdate) error
+	PaymentByProviderID(ctx context.Context, provider, providerID string) (Payment, error)
+	// PaymentsToSettle — заблокированные платежи завершенных, отмененных и проваленных заказов
+	PaymentsToSettle(ctx context.Context, limit int) ([]Settlement, error)
+
+	// StartTip заводит чаевые после доставки за заказ владельца, если с доставки прошло не больше window.
+	// Чаевые, на которые банк отказал, можно начать заново, а зависшую в pending попытку — повторить
+	// с прежней суммой после tipAttemptTimeout; остальные — ErrTipExists
+	StartTip(ctx context.Context, userID, orderID int64, amount money.Money, provider string, window time.Duration) (Tip, error)
+	UpdateTip(ctx context.Context, id int64, st TipStatus, providerID, errMsg string) error
+	GetTip(ctx context.Context, userID, orderID int64) (Tip, error)
+
+	// CreateReview сохраняет отзыв владельца на доставленный заказ; курьер берется из заказа
+	CreateReview(ctx context.Context, rv review.Review) (review.Review, error)
+	GetReview(ctx context.Context, orderID int64) (review.Review, error)
+	// ListReviews — отзывы для модерации, от новых к старым
+	ListReviews(ctx context.Context, f review.Filter) ([]review.Review, error)
+	// ProductReviews — видимые отзывы с оценкой еды на заказы, в которых был товар
+	ProductReviews(ctx context.Context, productID, before int64, limit int) ([]review.Review, error)
+	// SetReviewHidden скрывает отзыв или возвращает его в рейтинги
+	SetReviewHidden(ctx context.Context, id int64, hidden bool, reason string) (review.Review, error)
+	ProductRating(ctx context.Context, productID int64) (review.Summary, error)
+	CourierRating(ctx context.Context, courierID int64) (review.Summary, error)
 }
 
 type pgRepo struct {
@@ -31,30 +197,524 @@ func New(db *pgxpool.Pool) Repository {
 	return &pgRepo{db: db}
 }
 
-func (r *pgRepo) CreateOrder(ctx context.Context, o Order) (int64, error) {
+func (r *pgRepo) CreateOrder(ctx context.Context, o Order, idem *Idempotency) (int64, bool, error) {
 	// Начинаем транзакцию
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	defer tx.Rollback(ctx) // Если что-то пойдет не так, изменения откатятся
 
+	// 0. Повтор запроса с тем же ключом отдает уже созданный заказ
+	if idem != nil {
+		existing, err := claimIdempotencyKey(ctx, tx, o.UserID, *idem)
+		if err != nil {
+			return 0, false, err
+		}
+		if existing != 0 {
+			return existing, true, nil
+		}
+	}
+
 	// 1. Создаем сам заказ
 	var orderID int64
-	err = tx.QueryRow(ctx, "INSERT INTO orders (user_id, total_price) VALUES ($1, $2) RETURNING id", o.UserID, o.TotalPrice).Scan(&orderID)
+	var promoID *int64
+	if o.Promo != nil {
+		promoID = &o.Promo.ID
+	}
+	d := o.Delivery
+	if d == nil {
+		d = &Delivery{Fee: money.Zero()}
+	}
+	// Новый заказ ждет оплаты; заказ ко времени сразу занимает место в своем слоте,
+	// а после оплаты уснет в статусе scheduled (см. AuthorizePayment)
+	initial := status.AwaitingPayment
+	var deliverAt, releaseAt *time.Time
+	if o.Slot != nil {
+		deliverAt, releaseAt = &o.Slot.DeliverAt, &o.Slot.ReleaseAt
+		if err := bookSlot(ctx, tx, o.Slot.DeliverAt, o.Slot.Capacity); err != nil {
+			return 0, false, err
+		}
+	}
+	query := `
+		INSERT INTO orders (user_id, status, total_price, discount, promo_code_id,
+			delivery_address, delivery_lat, delivery_lon, delivery_distance_km, delivery_fee, deliver_at, release_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12) RETURNING id`
+	err = tx.QueryRow(ctx, query, o.UserID, initial, o.TotalPrice, o.Discount, promoID,
+		d.Address, d.Lat, d.Lon, d.DistanceKm, d.Fee, deliverAt, releaseAt).Scan(&orderID)
 	if err != nil {
-		return 0, err
+		return 0, false, err
+	}
+	if o.Promo != nil {
+		if err := usePromoCode(ctx, tx, *o.Promo, o.UserID, orderID); err != nil {
+			return 0, false, err
+		}
+	}
+	if o.Tip.IsPositive() {
+		query := "INSERT INTO tips (order_id, amount, source) VALUES ($1, $2, $3)"
+		if _, err := tx.Exec(ctx, query, orderID, o.Tip, TipAtCheckout); err != nil {
+			return 0, false, err
+		}
+	}
+	if err := status.Record(ctx, tx, orderID, "", initial, status.Actor{Type: status.ActorClient, ID: o.UserID}, ""); err != nil {
+		return 0, false, err
+	}
+	if idem != nil {
+		query := "UPDATE order_idempotency_keys SET order_id = $3 WHERE user_id = $1 AND key = $2"
+		if _, err := tx.Exec(ctx, query, o.UserID, idem.Key, orderID); err != nil {
+			return 0, false, err
+		}
+	}
+
+	// 2. Резервируем остатки товаров, у которых они ведутся
+	if err := reserveStock(ctx, tx, orderID, o.Items); err != nil {
+		return 0, false, err
 	}
 
-	// 2. Добавляем каждый товар из заказа
+	// 3. Добавляем каждый товар из заказа
 	for _, item := range o.Items {
-		_, err = tx.Exec(ctx, "INSERT INTO order_items (order_id, product_id, quantity, price_at_purchase) VALUES ($1, $2, $3, $4)",
-			orderID, item.ProductID, item.Quantity, item.Price)
+		if err := checkAvailable(ctx, tx, item); err != nil {
+			return 0, false, err
+		}
+		var itemID int64
+		query := `
+			INSERT INTO order_items (order_id, product_id, quantity, price_at_purchase, discount, tax_category)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+		err = tx.QueryRow(ctx, query, orderID, item.ProductID, item.Quantity, item.Price, item.Discount, item.TaxCategory).Scan(&itemID)
 		if err != nil {
-			return 0, err
+			return 0, false, err
+		}
+
+		// 4. Сохраняем снимок выбранных опций (название и надбавка на момент заказа)
+		for _, opt := range item.Options {
+			_, err = tx.Exec(ctx, "INSERT INTO order_item_options (order_item_id, option_id, name, price_delta) VALUES ($1, $2, $3, $4)",
+				itemID, opt.OptionID, opt.Name, opt.PriceDelta)
+			if err != nil {
+				return 0, false, err
+			}
 		}
 	}
 
 	// Подтверждаем транзакцию
-	return orderID, tx.Commit(ctx)
+	return orderID, false, tx.Commit(ctx)
+}
+
+// reserveStock списывает порции со склада и записывает резерв за заказом.
+// Товары обходим по возрастанию id, чтобы параллельные заказы блокировали
+// строки products в одном порядке и не ловили взаимоблокировку.
+func reserveStock(ctx context.Context, tx pgx.Tx, orderID int64, items []OrderItem) error {
+	quantities := make(map[int64]int)
+	for _, item := range items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	ids := make([]int64, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var soldOut []int64
+	for _, id := range ids {
+		quantity := quantities[id]
+		var left int
+		query := "UPDATE products SET stock = stock - $2, updated_at = NOW() WHERE id = $1 AND stock >= $2 RETURNING stock"
+		err := tx.QueryRow(ctx, query, id, quantity).Scan(&left)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Строка не обновилась: либо остаток не ведется, либо его не хватает.
+			// Несуществующий товар здесь пропускаем — его отвергнет checkAvailable.
+			var stock *int
+			err := tx.QueryRow(ctx, "SELECT stock FROM products WHERE id = $1", id).Scan(&stock)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if stock != nil {
+				return fmt.Errorf("%w: товар %d, осталось %d", ErrOutOfStock, id, *stock)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if left == 0 {
+			soldOut = append(soldOut, id)
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO stock_reservations (order_id, product_id, quantity) VALUES ($1, $2, $3)", orderID, id, quantity)
+		if err != nil {
+			return err
+		}
+	}
+	// Каталог узнает о распродаже из outbox: событие уйдет, только если заказ сохранится
+	return enqueueStockAvailability(ctx, tx, soldOut, false)
+}
+
+// enqueueStockAvailability пишет в outbox каталога, что товары распроданы или снова в наличии
+func enqueueStockAvailability(ctx context.Context, tx pgx.Tx, productIDs []int64, available bool) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return events.Enqueue(ctx, tx, events.AvailabilityChanged, events.AvailabilityChangedData{
+		ProductIDs: productIDs, IsAvailable: available, Reason: events.ReasonStock,
+	})
+}
+
+// releaseReservations возвращает на склад все еще активные резервы заказов.
+// Если ресторан тем временем снял ограничение остатка (stock = NULL), возвращать некуда.
+func releaseReservations(ctx context.Context, tx pgx.Tx, orderIDs []int64) (int64, error) {
+	query := `
+		UPDATE products p SET stock = p.stock + r.quantity, updated_at = NOW()
+		FROM (
+			SELECT product_id, SUM(quantity) AS quantity FROM stock_reservations
+			WHERE order_id = ANY($1) AND released_at IS NULL GROUP BY product_id
+		) r
+		WHERE p.id = r.product_id AND p.stock IS NOT NULL
+		RETURNING p.id, p.stock = r.quantity AND p.is_available AND p.deleted_at IS NULL`
+	rows, err := tx.Query(ctx, query, orderIDs)
+	if err != nil {
+		return 0, err
+	}
+	// Товар снова можно заказать, если до возврата остаток был нулевым
+	var restocked []int64
+	var id int64
+	var back bool
+	_, err = pgx.ForEachRow(rows, []any{&id, &back}, func() error {
+		if back {
+			restocked = append(restocked, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := enqueueStockAvailability(ctx, tx, restocked, true); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(ctx, "UPDATE stock_reservations SET released_at = NOW() WHERE order_id = ANY($1) AND released_at IS NULL", orderIDs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *pgRepo) ChangeStatus(ctx context.Context, orderID int64, to status.Status, actor status.Actor, reason string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Отмена — не просто смена статуса: курьер, склад и событие для других сервисов
+	var released int64
+	if to == status.Cancelled {
+		_, released, err = cancel(ctx, tx, orderID, actor, reason)
+	} else {
+		_, err = status.Apply(ctx, tx, orderID, to, actor, reason)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Проваленный заказ больше не держит курьера
+	if to == status.Failed {
+		query := "UPDATE couriers SET is_available = TRUE WHERE id = (SELECT courier_id FROM orders WHERE id = $1)"
+		if _, err := tx.Exec(ctx, query, orderID); err != nil {
+			return 0, err
+		}
+	}
+	return released, tx.Commit(ctx)
+}
+
+func (r *pgRepo) CancelOrder(ctx context.Context, orderID int64, actor status.Actor, reason string) (Cancellation, int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return Cancellation{}, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	c, released, err := cancel(ctx, tx, orderID, actor, reason)
+	if err != nil {
+		return Cancellation{}, 0, err
+	}
+	return c, released, tx.Commit(ctx)
+}
+
+// cancel отменяет заказ в транзакции tx: клиент может отменить только свой заказ,
+// назначенный курьер освобождается, при бесплатной отмене порции возвращаются на склад,
+// а в outbox уходит order.cancelled. Возвращает итог отмены и число снятых резервов.
+func cancel(ctx context.Context, tx pgx.Tx, orderID int64, actor status.Actor, reason string) (Cancellation, int64, error) {
+	c := Cancellation{OrderID: orderID, Actor: orderevents.Actor(actor), Reason: reason}
+	var courierID *int64
+	err := tx.QueryRow(ctx, "SELECT user_id, courier_id FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&c.UserID, &courierID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && actor.Type == status.ActorClient && actor.ID != c.UserID) {
+		// Чужой заказ для клиента выглядит как несуществующий
+		return Cancellation{}, 0, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return Cancellation{}, 0, err
+	}
+
+	from, err := status.Apply(ctx, tx, orderID, status.Cancelled, actor, reason)
+	if err != nil {
+		return Cancellation{}, 0, err
+	}
+	c.From = string(from)
+	c.Free = status.FreeCancel(from)
+
+	if courierID != nil {
+		c.CourierID = *courierID
+		if _, err := tx.Exec(ctx, "UPDATE orders SET courier_id = NULL WHERE id = $1", orderID); err != nil {
+			return Cancellation{}, 0, err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE couriers SET is_available = TRUE WHERE id = $1", c.CourierID); err != nil {
+			return Cancellation{}, 0, err
+		}
+	}
+
+	// Промокод можно будет использовать снова, место в слоте доставки — занять другому заказу
+	if err := releasePromoCode(ctx, tx, orderID); err != nil {
+		return Cancellation{}, 0, err
+	}
+	if err := settleOnCancel(ctx, tx, orderID, actor, c.Free); err != nil {
+		return Cancellation{}, 0, err
+	}
+	if err := cancelCheckoutTip(ctx, tx, orderID); err != nil {
+		return Cancellation{}, 0, err
+	}
+	if err := releaseSlot(ctx, tx, orderID); err != nil {
+		return Cancellation{}, 0, err
+	}
+
+	// После начала готовки порции уже потрачены, на склад возвращать нечего
+	var released int64
+	if c.Free {
+		if released, err = releaseReservations(ctx, tx, []int64{orderID}); err != nil {
+			return Cancellation{}, 0, err
+		}
+	}
+	if err := orderevents.Enqueue(ctx, tx, orderID, orderevents.OrderCancelled, c); err != nil {
+		return Cancellation{}, 0, err
+	}
+	return c, released, nil
+}
+
+func (r *pgRepo) GetStatusHistory(ctx context.Context, orderID int64) ([]status.Change, error) {
+	history, err := status.History(ctx, r.db, orderID)
+	if err != nil {
+		return nil, err
+	}
+	// У любого заказа есть хотя бы запись о создании
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	return history, nil
+}
+
+func (r *pgRepo) OrderParties(ctx context.Context, orderID int64) (int64, int64, error) {
+	var userID, courierUserID int64
+	query := `
+		SELECT o.user_id, COALESCE(c.user_id, 0) FROM orders o
+		LEFT JOIN couriers c ON c.id = o.courier_id
+		WHERE o.id = $1`
+	err := r.db.QueryRow(ctx, query, orderID).Scan(&userID, &courierUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	return userID, courierUserID, err
+}
+
+func (r *pgRepo) ExpireOrders(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Заказы, которые прямо сейчас кто-то меняет, пропускаем: проверим на следующем тике
+	query := `
+		SELECT id FROM orders
+		WHERE status = 'awaiting_payment' AND created_at < NOW() - make_interval(secs => $1)
+		ORDER BY id FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var released int64
+	for _, id := range orderIDs {
+		_, n, err := cancel(ctx, tx, id, status.Actor{Type: status.ActorSystem}, ReasonExpired)
+		if err != nil {
+			return 0, 0, err
+		}
+		released += n
+	}
+	return int64(len(orderIDs)), released, tx.Commit(ctx)
+}
+
+// checkAvailable проверяет, что товар не удален, не стоит в стоп-листе и его цена вместе с надбавками
+// выбранных опций не поменялась с момента расчета заказа. FOR SHARE не дает ресторану снять товар
+// с продажи или поменять цены, пока транзакция заказа не завершилась.
+func checkAvailable(ctx context.Context, tx pgx.Tx, item OrderItem) error {
+	var available bool
+	var price money.Money
+	query := "SELECT is_available AND deleted_at IS NULL, price FROM products WHERE id = $1 FOR SHARE"
+	err := tx.QueryRow(ctx, query, item.ProductID).Scan(&available, &price)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+	if !available {
+		return fmt.Errorf("%w: %d", ErrProductUnavailable, item.ProductID)
+	}
+	if price.Cmp(item.BasePrice) != 0 {
+		return fmt.Errorf("%w: товар %d стоит %s", ErrPriceChanged, item.ProductID, price)
+	}
+	return checkOptionPrices(ctx, tx, item)
+}
+
+// checkOptionPrices сверяет надбавки выбранных опций с каталогом. Опцию, которой у товара
+// больше нет (меню пересобрали), считаем изменением цены: клиенту нужно пересчитать заказ.
+func checkOptionPrices(ctx context.Context, tx pgx.Tx, item OrderItem) error {
+	if len(item.Options) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(item.Options))
+	for i, o := range item.Options {
+		ids[i] = o.OptionID
+	}
+	query := `
+		SELECT o.id, o.price_delta FROM product_options o
+		JOIN product_option_groups g ON g.id = o.group_id
+		WHERE o.id = ANY($1) AND g.product_id = $2
+		FOR SHARE OF o`
+	rows, err := tx.Query(ctx, query, ids, item.ProductID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	deltas := make(map[int64]money.Money, len(ids))
+	for rows.Next() {
+		var id int64
+		var delta money.Money
+		if err := rows.Scan(&id, &delta); err != nil {
+			return err
+		}
+		deltas[id] = delta
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range item.Options {
+		delta, ok := deltas[o.OptionID]
+		if !ok {
+			return fmt.Errorf("%w: опции %d у товара %d больше нет", ErrPriceChanged, o.OptionID, item.ProductID)
+		}
+		if delta.Cmp(o.PriceDelta) != 0 {
+			return fmt.Errorf("%w: опция %d товара %d стоит %s", ErrPriceChanged, o.OptionID, item.ProductID, delta)
+		}
+	}
+	return nil
+}
+
+func (r *pgRepo) GetOptionGroups(ctx context.Context, productID int64) ([]OptionGroup, error) {
+	query := `
+		SELECT g.id, g.name, g.min_select, g.max_select, o.id, o.name, o.price_delta
+		FROM product_option_groups g
+		JOIN product_options o ON o.group_id = g.id
+		WHERE g.product_id = $1
+		ORDER BY g.position, g.id, o.position, o.id`
+	rows, err := r.db.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []OptionGroup
+	for rows.Next() {
+		var g OptionGroup
+		var o ItemOption
+		if err := rows.Scan(&g.ID, &g.Name, &g.MinSelect, &g.MaxSelect, &o.OptionID, &o.Name, &o.PriceDelta); err != nil {
+			return nil, err
+		}
+		if len(groups) == 0 || groups[len(groups)-1].ID != g.ID {
+			groups = append(groups, g)
+		}
+		last := &groups[len(groups)-1]
+		last.Options = append(last.Options, o)
+	}
+	return groups, rows.Err()
+}
+
+func (r *pgRepo) GetCatalogProducts(ctx context.Context, productIDs []int64) (map[int64]CatalogProduct, error) {
+	query := `
+		SELECT id, name, price, tax_category, is_available, COALESCE(kitchen_id, 0),
+			COALESCE(to_char(available_from, 'HH24:MI'), ''), COALESCE(to_char(available_until, 'HH24:MI'), ''),
+			stock IS NOT NULL, COALESCE(category_id, 0)
+		FROM products WHERE id = ANY($1) AND deleted_at IS NULL`
+	rows, err := r.db.Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64]CatalogProduct, len(productIDs))
+	var kitchenIDs []int64
+	for rows.Next() {
+		var id int64
+		var ps CatalogProduct
+		if err := rows.Scan(&id, &ps.Name, &ps.Price, &ps.TaxCategory, &ps.IsAvailable, &ps.KitchenID, &ps.AvailableFrom, &ps.AvailableUntil, &ps.LimitedStock, &ps.CategoryID); err != nil {
+			return nil, err
+		}
+		result[id] = ps
+		if ps.KitchenID != 0 {
+			kitchenIDs = append(kitchenIDs, ps.KitchenID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(kitchenIDs) == 0 {
+		return result, nil
+	}
+
+	schedules, err := schedule.Load(ctx, r.db, kitchenIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Координаты кухонь нужны для расчета доставки; кухня без координат расстояние не задает.
+	// Каталог хранит незаполненные координаты как 0, 0 — это тоже "не задано"
+	query = `
+		SELECT id, lat, lon FROM kitchens
+		WHERE id = ANY($1) AND lat IS NOT NULL AND lon IS NOT NULL AND NOT (lat = 0 AND lon = 0)`
+	rows, err = r.db.Query(ctx, query, kitchenIDs)
+	if err != nil {
+		return nil, err
+	}
+	locations := make(map[int64]geo.Point)
+	var kitchenID int64
+	var point geo.Point
+	_, err = pgx.ForEachRow(rows, []any{&kitchenID, &point.Lat, &point.Lon}, func() error {
+		locations[kitchenID] = point
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, ps := range result {
+		ps.Kitchen = schedules[ps.KitchenID]
+		if loc, ok := locations[ps.KitchenID]; ok {
+			ps.KitchenLocation = &loc
+		}
+		result[id] = ps
+	}
+	return result, nil
 }
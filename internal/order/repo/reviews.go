@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/review"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const reviewColumns = `
+	SELECT id, order_id, user_id, COALESCE(courier_id, 0), food_score, COALESCE(food_comment, ''),
+		courier_score, COALESCE(courier_comment, ''), hidden, COALESCE(hidden_reason, ''), moderated_at, created_at
+	FROM reviews rv`
+
+func scanReview(row pgx.Row) (review.Review, error) {
+	var rv review.Review
+	var foodScore, courierScore *int
+	var foodComment, courierComment string
+	err := row.Scan(&rv.ID, &rv.OrderID, &rv.UserID, &rv.CourierID, &foodScore, &foodComment,
+		&courierScore, &courierComment, &rv.Hidden, &rv.HiddenReason, &rv.ModeratedAt, &rv.CreatedAt)
+	if foodScore != nil {
+		rv.Food = &review.Rating{Score: *foodScore, Comment: foodComment}
+	}
+	if courierScore != nil {
+		rv.Courier = &review.Rating{Score: *courierScore, Comment: courierComment}
+	}
+	return rv, err
+}
+
+func collectReviews(rows pgx.Rows) ([]review.Review, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (review.Review, error) {
+		return scanReview(row)
+	})
+}
+
+func (r *pgRepo) CreateReview(ctx context.Context, rv review.Review) (review.Review, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return review.Review{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var owner int64
+	var st status.Status
+	var courierID *int64
+	query := "SELECT user_id, status, courier_id FROM orders WHERE id = $1"
+	err = tx.QueryRow(ctx, query, rv.OrderID).Scan(&owner, &st, &courierID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && owner != rv.UserID) {
+		return review.Review{}, fmt.Errorf("%w: %d", status.ErrOrderNotFound, rv.OrderID)
+	}
+	if err != nil {
+		return review.Review{}, err
+	}
+	if st != status.Completed {
+		return review.Review{}, fmt.Errorf("%w: заказ %d в статусе %s", review.ErrNotReviewable, rv.OrderID, st)
+	}
+	if rv.Courier != nil && courierID == nil {
+		return review.Review{}, review.ErrNoCourier
+	}
+	// Курьера запоминаем, только если его оценили: иначе отзыв к нему не относится
+	var reviewedCourier *int64
+	var foodScore, courierScore *int
+	var foodComment, courierComment *string
+	if rv.Food != nil {
+		foodScore, foodComment = &rv.Food.Score, &rv.Food.Comment
+	}
+	if rv.Courier != nil {
+		reviewedCourier, courierScore, courierComment = courierID, &rv.Courier.Score, &rv.Courier.Comment
+		rv.CourierID = *courierID
+	}
+
+	query = `
+		INSERT INTO reviews (order_id, user_id, courier_id, food_score, food_comment, courier_score, courier_comment)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''))
+		RETURNING id, created_at`
+	err = tx.QueryRow(ctx, query, rv.OrderID, rv.UserID, reviewedCourier, foodScore, foodComment, courierScore, courierComment).
+		Scan(&rv.ID, &rv.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return review.Review{}, fmt.Errorf("%w: заказ %d", review.ErrDuplicate, rv.OrderID)
+	}
+	if err != nil {
+		return review.Review{}, err
+	}
+	return rv, tx.Commit(ctx)
+}
+
+func (r *pgRepo) GetReview(ctx context.Context, orderID int64) (review.Review, error) {
+	rv, err := scanReview(r.db.QueryRow(ctx, reviewColumns+" WHERE order_id = $1", orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return review.Review{}, fmt.Errorf("%w: заказ %d", review.ErrNotFound, orderID)
+	}
+	return rv, err
+}
+
+func (r *pgRepo) ListReviews(ctx context.Context, f review.Filter) ([]review.Review, error) {
+	query := reviewColumns + `
+		WHERE ($1::boolean IS NULL OR hidden = $1)
+			AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`
+	rows, err := r.db.Query(ctx, query, f.Hidden, f.Before, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectReviews(rows)
+}
+
+func (r *pgRepo) ProductReviews(ctx context.Context, productID, before int64, limit int) ([]review.Review, error) {
+	query := reviewColumns + `
+		WHERE NOT hidden AND food_score IS NOT NULL
+			AND EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = rv.order_id AND oi.product_id = $1)
+			AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`
+	rows, err := r.db.Query(ctx, query, productID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectReviews(rows)
+}
+
+func (r *pgRepo) SetReviewHidden(ctx context.Context, id int64, hidden bool, reason string) (review.Review, error) {
+	query := `
+		UPDATE reviews SET hidden = $2, hidden_reason = NULLIF($3, ''), moderated_at = NOW()
+		WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, id, hidden, reason)
+	if err != nil {
+		return review.Review{}, err
+	}
+	if result.RowsAffected() == 0 {
+		return review.Review{}, fmt.Errorf("%w: %d", review.ErrNotFound, id)
+	}
+	return scanReview(r.db.QueryRow(ctx, reviewColumns+" WHERE id = $1", id))
+}
+
+func (r *pgRepo) ProductRating(ctx context.Context, productID int64) (review.Summary, error) {
+	// Товар мог быть в заказе несколькими позициями (с разными опциями) — оценка заказа считается один раз
+	query := `
+		SELECT food_score, COUNT(*) FROM reviews rv
+		WHERE NOT hidden AND food_score IS NOT NULL
+			AND EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = rv.order_id AND oi.product_id = $1)
+		GROUP BY food_score`
+	return r.ratingSummary(ctx, query, productID)
+}
+
+func (r *pgRepo) CourierRating(ctx context.Context, courierID int64) (review.Summary, error) {
+	query := `
+		SELECT courier_score, COUNT(*) FROM reviews
+		WHERE NOT hidden AND courier_score IS NOT NULL AND courier_id = $1
+		GROUP BY courier_score`
+	return r.ratingSummary(ctx, query, courierID)
+}
+
+// ratingSummary собирает Summary из пар (оценка, сколько раз)
+func (r *pgRepo) ratingSummary(ctx context.Context, query string, id int64) (review.Summary, error) {
+	var s review.Summary
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	sum := 0
+	for rows.Next() {
+		var score, count int
+		if err := rows.Scan(&score, &count); err != nil {
+			return s, err
+		}
+		s.Scores[score-review.MinScore] = count
+		s.Count += count
+		sum += score * count
+	}
+	if s.Count > 0 {
+		s.Average = math.Round(float64(sum)/float64(s.Count)*100) / 100
+	}
+	return s, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSlotFull — на этот слот доставки заказов больше не берем
+var ErrSlotFull = errors.New("на это время заказов уже слишком много, выберите другой слот")
+
+// ReasonReleased — причина перевода заказа ко времени на кухню
+const ReasonReleased = "пора готовить заказ ко времени"
+
+// Slot — заказ ко времени: до ReleaseAt он спит в статусе scheduled, и кухня с курьерами его не видят
+type Slot struct {
+	DeliverAt time.Time `json:"deliver_at"` // начало слота доставки
+	ReleaseAt time.Time `json:"release_at"` // когда заказ уйдет на кухню
+	Capacity  int       `json:"-"`          // сколько заказов принимает слот
+}
+
+// bookSlot занимает место в слоте. Счетчик меняется одним UPDATE с проверкой лимита,
+// поэтому параллельные заказы не запишут в слот больше capacity.
+func bookSlot(ctx context.Context, tx pgx.Tx, slot time.Time, capacity int) error {
+	query := `
+		INSERT INTO delivery_slots (slot_start, booked) VALUES ($1, 1)
+		ON CONFLICT (slot_start) DO UPDATE SET booked = delivery_slots.booked + 1
+		WHERE delivery_slots.booked < $2`
+	result, err := tx.Exec(ctx, query, slot, capacity)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSlotFull, slot.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// releaseSlot освобождает место отмененного заказа ко времени
+func releaseSlot(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	query := `
+		UPDATE delivery_slots SET booked = booked - 1
+		WHERE slot_start = (SELECT deliver_at FROM orders WHERE id = $1) AND booked > 0`
+	_, err := tx.Exec(ctx, query, orderID)
+	return err
+}
+
+func (r *pgRepo) ReleaseScheduled(ctx context.Context) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Заказы, которые сейчас отменяют, пропускаем: проверим на следующем тике
+	query := `
+		SELECT id FROM orders
+		WHERE status = 'scheduled' AND release_at <= NOW()
+		ORDER BY release_at, id FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range orderIDs {
+		if _, err := status.Apply(ctx, tx, id, status.New, status.Actor{Type: status.ActorSystem}, ReasonReleased); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE orders SET released_at = NOW() WHERE id = $1", id); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(orderIDs)), tx.Commit(ctx)
+}
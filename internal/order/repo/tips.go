@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrNotTippable — заказ еще не доставлен, чаевые пока некому отдать
+	ErrNotTippable = errors.New("чаевые можно оставить только за доставленный заказ")
+	// ErrTipExists — у заказа уже есть чаевые: при оформлении или после доставки
+	ErrTipExists = errors.New("чаевые за этот заказ уже оставлены")
+	// ErrTipWindowClosed — после доставки прошло больше окна для чаевых
+	ErrTipWindowClosed = errors.New("время, чтобы оставить чаевые за этот заказ, вышло")
+)
+
+// tipAttemptTimeout — сколько ждем попытку списания чаевых после доставки. Если сервис упал
+// между записью pending и ответом провайдера, после этого срока клиент может повторить запрос.
+const tipAttemptTimeout = 5 * time.Minute
+
+// Когда оставлены чаевые
+const (
+	TipAtCheckout    = "checkout"
+	TipAfterDelivery = "after_delivery"
+)
+
+type TipStatus string
+
+const (
+	TipPending   TipStatus = "pending"   // ждем доставки или ответа провайдера
+	TipPaid      TipStatus = "paid"      // списаны, курьер их получит
+	TipFailed    TipStatus = "failed"    // банк отказал, после доставки можно попробовать снова
+	TipCancelled TipStatus = "cancelled" // заказ не доставлен, чаевые не списывались
+)
+
+type Tip struct {
+	ID        int64       `json:"id"`
+	OrderID   int64       `json:"order_id"`
+	Amount    money.Money `json:"amount"`
+	Source    string      `json:"source"`
+	Status    TipStatus   `json:"status"`
+	Attempt   int         `json:"-"`
+	LastError string      `json:"last_error,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	PaidAt    *time.Time  `json:"paid_at,omitempty"`
+}
+
+const tipColumns = `
+	SELECT id, order_id, amount, source, status, attempt, COALESCE(last_error, ''), created_at, paid_at
+	FROM tips`
+
+func scanTip(row pgx.Row) (Tip, error) {
+	var t Tip
+	err := row.Scan(&t.ID, &t.OrderID, &t.Amount, &t.Source, &t.Status, &t.Attempt, &t.LastError, &t.CreatedAt, &t.PaidAt)
+	return t, err
+}
+
+func (r *pgRepo) GetTip(ctx context.Context, userID, orderID int64) (Tip, error) {
+	query := tipColumns + " WHERE order_id = (SELECT id FROM orders WHERE id = $1 AND user_id = $2)"
+	t, err := scanTip(r.db.QueryRow(ctx, query, orderID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Tip{}, fmt.Errorf("%w: заказ %d", payment.ErrNotFound, orderID)
+	}
+	return t, err
+}
+
+func (r *pgRepo) StartTip(ctx context.Context, userID, orderID int64, amount money.Money, provider string, window time.Duration) (Tip, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return Tip{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка заказа не дает двум запросам завести двое чаевых
+	var owner int64
+	var st status.Status
+	var hasCourier bool
+	query := "SELECT user_id, status, courier_id IS NOT NULL FROM orders WHERE id = $1 FOR UPDATE"
+	err = tx.QueryRow(ctx, query, orderID).Scan(&owner, &st, &hasCourier)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && owner != userID) {
+		return Tip{}, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return Tip{}, err
+	}
+	if st != status.Completed || !hasCourier {
+		return Tip{}, fmt.Errorf("%w: заказ %d в статусе %s", ErrNotTippable, orderID, st)
+	}
+	var open bool
+	query = `
+		SELECT MAX(created_at) > NOW() - make_interval(secs => $2) FROM order_status_history
+		WHERE order_id = $1 AND to_status = 'completed'`
+	if err := tx.QueryRow(ctx, query, orderID, window.Seconds()).Scan(&open); err != nil {
+		return Tip{}, err
+	}
+	if !open {
+		return Tip{}, ErrTipWindowClosed
+	}
+
+	var stale bool
+	query = tipColumns + " WHERE order_id = $1"
+	t, err := scanTip(tx.QueryRow(ctx, query, orderID))
+	if err == nil && t.Source == TipAfterDelivery && t.Status == TipPending {
+		query = "SELECT attempted_at < NOW() - make_interval(secs => $2) FROM tips WHERE id = $1"
+		if err := tx.QueryRow(ctx, query, t.ID, tipAttemptTimeout.Seconds()).Scan(&stale); err != nil {
+			return Tip{}, err
+		}
+	}
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		query := `
+			INSERT INTO tips (order_id, amount, source, provider) VALUES ($1, $2, $3, $4)
+			RETURNING id, order_id, amount, source, status, attempt, '', created_at, paid_at`
+		if t, err = scanTip(tx.QueryRow(ctx, query, orderID, amount, TipAfterDelivery, provider)); err != nil {
+			return Tip{}, err
+		}
+	case err != nil:
+		return Tip{}, err
+	case t.Status == TipFailed:
+		// Банк отказал — пробуем заново, возможно с другой картой и суммой
+		query := `
+			UPDATE tips SET amount = $2, status = 'pending', provider = $3, provider_payment_id = NULL,
+				attempt = attempt + 1, last_error = NULL, attempted_at = NOW()
+			WHERE id = $1
+			RETURNING id, order_id, amount, source, status, attempt, '', created_at, paid_at`
+		if t, err = scanTip(tx.QueryRow(ctx, query, t.ID, amount, provider)); err != nil {
+			return Tip{}, err
+		}
+	case stale:
+		// Прошлая попытка зависла: сервис упал, не дождавшись провайдера. Повторяем ту же попытку
+		// с той же суммой — ключи идемпотентности не меняются, и провайдер не спишет деньги дважды.
+		if _, err := tx.Exec(ctx, "UPDATE tips SET attempted_at = NOW() WHERE id = $1", t.ID); err != nil {
+			return Tip{}, err
+		}
+	default:
+		return Tip{}, fmt.Errorf("%w: заказ %d", ErrTipExists, orderID)
+	}
+	return t, tx.Commit(ctx)
+}
+
+func (r *pgRepo) UpdateTip(ctx context.Context, id int64, st TipStatus, providerID, errMsg string) error {
+	query := `
+		UPDATE tips SET status = $2, provider_payment_id = COALESCE(NULLIF($3, ''), provider_payment_id),
+			last_error = NULLIF($4, ''), paid_at = CASE WHEN $2 = 'paid' THEN NOW() END
+		WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, id, st, providerID, errMsg)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: чаевые %d", payment.ErrNotFound, id)
+	}
+	return nil
+}
+
+// settleCheckoutTip закрывает чаевые, оставленные при оформлении, когда завершился платеж заказа:
+// списанные за доставленный заказ достаются курьеру, в остальных случаях их не списывали
+func settleCheckoutTip(ctx context.Context, tx pgx.Tx, orderID int64, to payment.Status) error {
+	query := `
+		UPDATE tips t SET
+			status = CASE WHEN $2 AND o.status = 'completed' THEN 'paid' ELSE 'cancelled' END,
+			paid_at = CASE WHEN $2 AND o.status = 'completed' THEN NOW() END
+		FROM orders o
+		WHERE t.order_id = $1 AND o.id = t.order_id AND t.source = 'checkout' AND t.status = 'pending'`
+	_, err := tx.Exec(ctx, query, orderID, to == payment.Captured)
+	return err
+}
+
+// cancelCheckoutTip — заказ отменен, курьер его не доставит: чаевые при оформлении не списываем
+func cancelCheckoutTip(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	query := "UPDATE tips SET status = 'cancelled' WHERE order_id = $1 AND source = 'checkout' AND status = 'pending'"
+	_, err := tx.Exec(ctx, query, orderID)
+	return err
+}
@@ -0,0 +1,100 @@
+// Package review — оценки заказов. Клиент ставит еде и курьеру отдельные оценки 1–5
+// с комментарием, один раз и только за свой доставленный заказ. Оценка еды идет всем
+// товарам заказа, оценка курьера — курьеру, который его вез. Скрытые модератором отзывы
+// в рейтингах не участвуют.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalid       = errors.New("некорректный отзыв")
+	ErrNotFound      = errors.New("отзыв не найден")
+	ErrDuplicate     = errors.New("отзыв на этот заказ уже оставлен")
+	ErrNotReviewable = errors.New("оценить можно только доставленный заказ")
+	ErrNoCourier     = errors.New("заказ доставлял не курьер, оценивать некого")
+)
+
+const (
+	MinScore = 1
+	MaxScore = 5
+
+	maxCommentLen = 1000
+	// maxReasonLen — причина скрытия отзыва модератором
+	maxReasonLen = 500
+)
+
+// Rating — оценка и комментарий к ней
+type Rating struct {
+	Score   int    `json:"score"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type Review struct {
+	ID        int64   `json:"id"`
+	OrderID   int64   `json:"order_id"`
+	UserID    int64   `json:"user_id,omitempty"`
+	CourierID int64   `json:"courier_id,omitempty"`
+	Food      *Rating `json:"food,omitempty"`    // nil — еду не оценивали
+	Courier   *Rating `json:"courier,omitempty"` // nil — курьера не оценивали
+	// Hidden — отзыв скрыт модератором: его видит только автор, в рейтинг он не идет
+	Hidden       bool       `json:"hidden"`
+	HiddenReason string     `json:"hidden_reason,omitempty"`
+	ModeratedAt  *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Summary — средняя оценка товара или курьера по видимым отзывам
+type Summary struct {
+	Average float64 `json:"average"` // 0, пока оценок нет
+	Count   int     `json:"count"`
+	// Scores — сколько раз ставили каждую оценку: Scores[0] — единицы, Scores[4] — пятерки
+	Scores [MaxScore]int `json:"scores"`
+}
+
+// Filter — выборка отзывов для модерации
+type Filter struct {
+	Hidden *bool // nil — все
+	Before int64 // id отзыва, с которого продолжить; 0 — с самых новых
+	Limit  int
+}
+
+// Validate проверяет оценки нового отзыва и обрезает пробелы в комментариях
+func Validate(r *Review) error {
+	if r.Food == nil && r.Courier == nil {
+		return fmt.Errorf("%w: оцените еду, курьера или обоих", ErrInvalid)
+	}
+	for _, rt := range []struct {
+		name   string
+		rating *Rating
+	}{{"еды", r.Food}, {"курьера", r.Courier}} {
+		if rt.rating == nil {
+			continue
+		}
+		if rt.rating.Score < MinScore || rt.rating.Score > MaxScore {
+			return fmt.Errorf("%w: оценка %s должна быть от %d до %d", ErrInvalid, rt.name, MinScore, MaxScore)
+		}
+		rt.rating.Comment = strings.TrimSpace(rt.rating.Comment)
+		if utf8.RuneCountInString(rt.rating.Comment) > maxCommentLen {
+			return fmt.Errorf("%w: комментарий длиннее %d символов", ErrInvalid, maxCommentLen)
+		}
+	}
+	return nil
+}
+
+// ValidateReason проверяет причину, по которой модератор скрывает отзыв
+func ValidateReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: укажите причину скрытия", ErrInvalid)
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLen {
+		return "", fmt.Errorf("%w: причина длиннее %d символов", ErrInvalid, maxReasonLen)
+	}
+	return reason, nil
+}
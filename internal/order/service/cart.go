@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/cart"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+// ErrEmptyCart — оформлять нечего
+var ErrEmptyCart = errors.New("корзина пуста")
+
+// CartLine — позиция корзины с ценой из каталога на момент чтения
+type CartLine struct {
+	ID        string            `json:"id"` // для PATCH и DELETE /cart/items/{id}
+	ProductID int64             `json:"product_id"`
+	Name      string            `json:"name,omitempty"`
+	Quantity  int               `json:"quantity"`
+	Options   []repo.ItemOption `json:"options,omitempty"`
+	Price     money.Money       `json:"price"`  // за штуку вместе с опциями
+	Amount    money.Money       `json:"amount"` // Price * Quantity
+	// Problem — почему позицию сейчас нельзя заказать (стоп-лист, кухня закрыта, опции больше нет).
+	// Такая позиция остается в корзине, но в сумму не входит, а оформление с ней не пройдет.
+	Problem string `json:"problem,omitempty"`
+}
+
+// Cart — корзина, пересчитанная по каталогу
+type Cart struct {
+	Items    []CartLine  `json:"items"`
+	Subtotal money.Money `json:"subtotal"` // сумма позиций без проблем, без скидки и доставки
+	// Ready — все позиции можно заказать прямо сейчас
+	Ready bool `json:"ready"`
+}
+
+// CheckoutRequest — все для заказа, кроме позиций: их возьмем из корзины
+type CheckoutRequest struct {
+	UserID         int64
+	PromoCode      string
+	Delivery       *repo.Delivery
+	DeliverAt      *time.Time
+	Tip            money.Money
+	IdempotencyKey string
+}
+
+type CartService interface {
+	// Get отдает корзину с актуальными ценами; пустая корзина — не ошибка
+	Get(ctx context.Context, userID int64) (Cart, error)
+	// AddItem проверяет товар и опции по каталогу и кладет позицию в корзину
+	AddItem(ctx context.Context, userID int64, item repo.OrderItem) (Cart, error)
+	// SetQuantity меняет количество; 0 убирает позицию
+	SetQuantity(ctx context.Context, userID int64, lineID string, quantity int) (Cart, error)
+	RemoveItem(ctx context.Context, userID int64, lineID string) (Cart, error)
+	Clear(ctx context.Context, userID int64) error
+	// Checkout оформляет заказ из корзины через PlaceOrder и очищает ее.
+	// Повтор с тем же Idempotency-Key вернет тот же заказ, даже если корзина уже пуста.
+	Checkout(ctx context.Context, req CheckoutRequest) (id int64, replayed bool, err error)
+}
+
+type cartService struct {
+	store  cart.Store
+	repo   repo.Repository
+	orders Service
+}
+
+func NewCart(store cart.Store, r repo.Repository, orders Service) CartService {
+	return &cartService{store: store, repo: r, orders: orders}
+}
+
+func (s *cartService) Get(ctx context.Context, userID int64) (Cart, error) {
+	lines, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return Cart{}, err
+	}
+	return s.price(ctx, lines)
+}
+
+func (s *cartService) AddItem(ctx context.Context, userID int64, item repo.OrderItem) (Cart, error) {
+	if item.Quantity <= 0 {
+		return Cart{}, fmt.Errorf("%w (товар %d)", ErrInvalidQty, item.ProductID)
+	}
+	if item.Quantity > cart.MaxQuantity {
+		return Cart{}, cart.ErrTooMany
+	}
+	optionIDs := make([]int64, len(item.Options))
+	for i, opt := range item.Options {
+		optionIDs[i] = opt.OptionID
+	}
+	line := cart.NewLine(item.ProductID, optionIDs, item.Quantity)
+
+	// В корзину кладем только то, что есть в каталоге и с допустимыми опциями;
+	// закрытая кухня не мешает — корзину часто собирают заранее
+	products, err := s.repo.GetCatalogProducts(ctx, []int64{item.ProductID})
+	if err != nil {
+		return Cart{}, err
+	}
+	items := []repo.OrderItem{item}
+	if err := resolvePrices(items, products); err != nil {
+		return Cart{}, err
+	}
+	if err := applyOptions(ctx, s.repo, &items[0]); err != nil {
+		return Cart{}, err
+	}
+
+	if err := s.store.Add(ctx, userID, line); err != nil {
+		return Cart{}, err
+	}
+	return s.Get(ctx, userID)
+}
+
+func (s *cartService) SetQuantity(ctx context.Context, userID int64, lineID string, quantity int) (Cart, error) {
+	switch {
+	case quantity == 0:
+		return s.RemoveItem(ctx, userID, lineID)
+	case quantity < 0:
+		return Cart{}, ErrInvalidQty
+	case quantity > cart.MaxQuantity:
+		return Cart{}, cart.ErrTooMany
+	}
+	if err := s.store.SetQuantity(ctx, userID, lineID, quantity); err != nil {
+		return Cart{}, err
+	}
+	return s.Get(ctx, userID)
+}
+
+func (s *cartService) RemoveItem(ctx context.Context, userID int64, lineID string) (Cart, error) {
+	if err := s.store.Remove(ctx, userID, lineID); err != nil {
+		return Cart{}, err
+	}
+	return s.Get(ctx, userID)
+}
+
+func (s *cartService) Clear(ctx context.Context, userID int64) error {
+	return s.store.Clear(ctx, userID)
+}
+
+func (s *cartService) Checkout(ctx context.Context, req CheckoutRequest) (int64, bool, error) {
+	lines, err := s.store.Get(ctx, req.UserID)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(lines) == 0 {
+		// Ответ на первое оформление мог потеряться: корзина уже очищена, а заказ есть
+		if req.IdempotencyKey != "" {
+			last, err := s.store.LastCheckout(ctx, req.UserID)
+			if err != nil {
+				return 0, false, err
+			}
+			if last.Key == req.IdempotencyKey {
+				return last.OrderID, true, nil
+			}
+		}
+		return 0, false, ErrEmptyCart
+	}
+
+	items := make([]repo.OrderItem, len(lines))
+	for i, line := range lines {
+		items[i] = repo.OrderItem{ProductID: line.ProductID, Quantity: line.Quantity}
+		for _, id := range line.OptionIDs {
+			items[i].Options = append(items[i].Options, repo.ItemOption{OptionID: id})
+		}
+	}
+	id, replayed, err := s.orders.PlaceOrder(ctx, OrderRequest{
+		UserID:         req.UserID,
+		Items:          items,
+		PromoCode:      req.PromoCode,
+		Delivery:       req.Delivery,
+		DeliverAt:      req.DeliverAt,
+		Tip:            req.Tip,
+		IdempotencyKey: req.IdempotencyKey,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	// Заказ уже создан: если корзину очистить не удалось, покупатель увидит ее снова, но второй заказ
+	// по тому же ключу не получится
+	if err := s.store.CheckedOut(ctx, req.UserID, cart.Checkout{Key: req.IdempotencyKey, OrderID: id}); err != nil {
+		return id, replayed, fmt.Errorf("заказ %d создан, но корзину очистить не удалось: %w", id, err)
+	}
+	return id, replayed, nil
+}
+
+// price пересчитывает корзину по каталогу: цены, названия и опции всегда текущие.
+// Позиция, которую сейчас нельзя заказать, не ломает корзину, а получает Problem.
+func (s *cartService) price(ctx context.Context, lines []cart.Line) (Cart, error) {
+	c := Cart{Items: make([]CartLine, 0, len(lines)), Subtotal: money.Zero(), Ready: true}
+	if len(lines) == 0 {
+		return c, nil
+	}
+	ids := make([]int64, len(lines))
+	for i, line := range lines {
+		ids[i] = line.ProductID
+	}
+	products, err := s.repo.GetCatalogProducts(ctx, ids)
+	if err != nil {
+		return Cart{}, err
+	}
+
+	now := time.Now()
+	for _, line := range lines {
+		item := repo.OrderItem{ProductID: line.ProductID, Quantity: line.Quantity}
+		for _, id := range line.OptionIDs {
+			item.Options = append(item.Options, repo.ItemOption{OptionID: id})
+		}
+		cl := CartLine{
+			ID:        line.ID(),
+			ProductID: line.ProductID,
+			Name:      products[line.ProductID].Name,
+			Quantity:  line.Quantity,
+			Price:     money.Zero(),
+			Amount:    money.Zero(),
+		}
+		items := []repo.OrderItem{item}
+		err := resolvePrices(items, products)
+		if err == nil {
+			err = applyOptions(ctx, s.repo, &items[0])
+		}
+		if err == nil {
+			err = checkOpen(items, products, now)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, repo.ErrProductNotFound), errors.Is(err, repo.ErrProductUnavailable),
+			errors.Is(err, ErrInvalidOptions), errors.Is(err, ErrKitchenClosed):
+			cl.Problem = err.Error()
+		default:
+			return Cart{}, err
+		}
+
+		cl.Options = items[0].Options
+		if cl.Problem == "" {
+			cl.Price = items[0].Price
+			cl.Amount = cl.Price.Mul(cl.Quantity)
+			c.Subtotal = c.Subtotal.Add(cl.Amount)
+		} else {
+			c.Ready = false
+		}
+		c.Items = append(c.Items, cl)
+	}
+	return c, nil
+}
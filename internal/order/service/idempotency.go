@@ -0,0 +1,60 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
+)
+
+const maxIdempotencyKeyLen = 255
+
+// validIdempotencyKey — обычно это UUID, но подойдет любая строка из печатных ASCII
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestHash — отпечаток того, что клиент заказывает и куда. Цены из запроса не учитываем:
+// сервер их все равно перезаписывает, а в повторе они могут быть уже другими.
+func requestHash(req OrderRequest) string {
+	type line struct {
+		ProductID int64   `json:"p"`
+		Quantity  int     `json:"q"`
+		Options   []int64 `json:"o,omitempty"`
+	}
+	lines := make([]line, len(req.Items))
+	for i, item := range req.Items {
+		lines[i] = line{ProductID: item.ProductID, Quantity: item.Quantity}
+		for _, opt := range item.Options {
+			lines[i].Options = append(lines[i].Options, opt.OptionID)
+		}
+	}
+	fingerprint := struct {
+		Lines   []line      `json:"l"`
+		Promo   string      `json:"c,omitempty"`
+		Address string      `json:"a,omitempty"`
+		Point   *[2]float64 `json:"g,omitempty"`
+		Slot    int64       `json:"s,omitempty"`
+		Tip     int64       `json:"t,omitempty"`
+	}{Lines: lines, Promo: promo.Normalize(req.PromoCode), Tip: req.Tip.Amount}
+	if req.Delivery != nil {
+		fingerprint.Address = strings.TrimSpace(req.Delivery.Address)
+		fingerprint.Point = &[2]float64{req.Delivery.Lat, req.Delivery.Lon}
+	}
+	if req.DeliverAt != nil {
+		fingerprint.Slot = req.DeliverAt.Unix()
+	}
+	data, _ := json.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
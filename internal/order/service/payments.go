@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/receipt"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+var (
+	// ErrNoPaymentToken — нечем платить
+	ErrNoPaymentToken = errors.New("укажите токен карты")
+	// ErrPaymentProvider — провайдер не ответил или ответил ошибкой; платеж можно повторить
+	ErrPaymentProvider = errors.New("платежный провайдер недоступен, попробуйте еще раз")
+	// ErrNoReceipt — деньги за заказ еще не списаны (или блокировку сняли), чека нет
+	ErrNoReceipt = errors.New("чек появится, когда деньги за заказ будут списаны")
+)
+
+// settleBatch — сколько платежей списываем или возвращаем за один тик
+const settleBatch = 100
+
+type PaymentService interface {
+	// Pay блокирует сумму заказа на карте. При успехе заказ уходит на кухню, при отказе банка
+	// возвращается ErrDeclined, и можно заплатить снова. pending — ждем вебхук провайдера.
+	Pay(ctx context.Context, userID, orderID int64, token string) (repo.Payment, error)
+	GetPayment(ctx context.Context, userID, orderID int64) (repo.Payment, error)
+	// HandleWebhook проверяет подпись уведомления провайдера и применяет его к платежу
+	HandleWebhook(ctx context.Context, body []byte, header http.Header) error
+	// SettlePayments списывает деньги за доставленные заказы и снимает блокировку с отмененных
+	SettlePayments(ctx context.Context) error
+	// Receipt — чек владельцу на сумму, которую реально списали за заказ
+	Receipt(ctx context.Context, userID, orderID int64) (receipt.Receipt, error)
+	// Tip — чаевые курьеру за доставленный заказ, отдельным платежом в течение окна после доставки.
+	// Списываются сразу; при отказе банка возвращается ErrDeclined, и можно попробовать снова.
+	// Если сервис упал посреди списания, повторный запрос через несколько минут доводит ту же попытку
+	Tip(ctx context.Context, userID, orderID int64, amount money.Money, token string) (repo.Tip, error)
+	GetTip(ctx context.Context, userID, orderID int64) (repo.Tip, error)
+}
+
+type paymentService struct {
+	repo      repo.Repository
+	provider  payment.Provider
+	seller    receipt.Seller
+	tipWindow time.Duration
+}
+
+func NewPayments(r repo.Repository, provider payment.Provider, seller receipt.Seller, tipWindow time.Duration) PaymentService {
+	return &paymentService{repo: r, provider: provider, seller: seller, tipWindow: tipWindow}
+}
+
+func (s *paymentService) Pay(ctx context.Context, userID, orderID int64, token string) (repo.Payment, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return repo.Payment{}, ErrNoPaymentToken
+	}
+	p, created, err := s.repo.StartPayment(ctx, userID, orderID, s.provider.Name())
+	if err != nil {
+		return repo.Payment{}, err
+	}
+	// Платеж уже идет или прошел: второй раз сумму не блокируем
+	if !created {
+		return p, nil
+	}
+
+	res, err := s.provider.Authorize(ctx, payment.AuthorizeRequest{
+		OrderID:        orderID,
+		Amount:         p.Amount,
+		Token:          token,
+		IdempotencyKey: fmt.Sprintf("payment-%d-%d", p.ID, p.Attempt),
+	})
+	if err != nil {
+		// Провайдер не ответил: платеж можно начать заново
+		if uerr := s.repo.UpdatePayment(ctx, p.ID, repo.PaymentUpdate{Status: payment.Failed, Error: err.Error()}); uerr != nil {
+			log.Printf("Платеж %d: не удалось записать ошибку провайдера: %v", p.ID, uerr)
+		}
+		return repo.Payment{}, fmt.Errorf("%w: заказ %d: %v", ErrPaymentProvider, orderID, err)
+	}
+	if err := s.repo.UpdatePayment(ctx, p.ID, repo.PaymentUpdate{Status: res.Status, ProviderID: res.ProviderID, Error: res.Reason}); err != nil {
+		return repo.Payment{}, err
+	}
+	if res.Status == payment.Declined {
+		return repo.Payment{}, fmt.Errorf("%w: %s", payment.ErrDeclined, res.Reason)
+	}
+	return s.repo.GetPayment(ctx, userID, orderID)
+}
+
+func (s *paymentService) GetPayment(ctx context.Context, userID, orderID int64) (repo.Payment, error) {
+	return s.repo.GetPayment(ctx, userID, orderID)
+}
+
+func (s *paymentService) HandleWebhook(ctx context.Context, body []byte, header http.Header) error {
+	res, err := s.provider.ParseWebhook(body, header)
+	if err != nil {
+		return err
+	}
+	p, err := s.repo.PaymentByProviderID(ctx, s.provider.Name(), res.ProviderID)
+	if err != nil {
+		return err
+	}
+	err = s.repo.UpdatePayment(ctx, p.ID, repo.PaymentUpdate{Status: res.Status, Error: res.Reason})
+	// Провайдеры повторяют вебхуки и не гарантируют порядок: устаревшее уведомление просто пропускаем
+	if errors.Is(err, payment.ErrIllegalTransition) {
+		log.Printf("Платеж %d: пропущен вебхук %s: %v", p.ID, res.Status, err)
+		return nil
+	}
+	return err
+}
+
+func (s *paymentService) Receipt(ctx context.Context, userID, orderID int64) (receipt.Receipt, error) {
+	p, err := s.repo.GetPayment(ctx, userID, orderID)
+	if errors.Is(err, payment.ErrNotFound) {
+		return receipt.Receipt{}, ErrNoReceipt
+	}
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+	// Пока сумма только заблокирована, продажи еще нет; снятая блокировка — тоже не продажа
+	if p.Status != payment.Captured || p.Settled == nil {
+		return receipt.Receipt{}, ErrNoReceipt
+	}
+	o, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+	return receipt.Build(o, *p.Settled, s.seller), nil
+}
+
+func (s *paymentService) Tip(ctx context.Context, userID, orderID int64, amount money.Money, token string) (repo.Tip, error) {
+	amount, err := validateTip(amount, true)
+	if err != nil {
+		return repo.Tip{}, err
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return repo.Tip{}, ErrNoPaymentToken
+	}
+	t, err := s.repo.StartTip(ctx, userID, orderID, amount, s.provider.Name(), s.tipWindow)
+	if err != nil {
+		return repo.Tip{}, err
+	}
+	// Повтор зависшей попытки идет с прежней суммой, иначе ключ идемпотентности не защитит от двойного списания
+	amount = t.Amount
+
+	fail := func(reason string) {
+		if err := s.repo.UpdateTip(ctx, t.ID, repo.TipFailed, "", reason); err != nil {
+			log.Printf("Чаевые %d: не удалось записать ошибку: %v", t.ID, err)
+		}
+	}
+	res, err := s.provider.Authorize(ctx, payment.AuthorizeRequest{
+		OrderID:        orderID,
+		Amount:         amount,
+		Token:          token,
+		IdempotencyKey: fmt.Sprintf("tip-%d-%d", t.ID, t.Attempt),
+	})
+	if err != nil {
+		fail(err.Error())
+		return repo.Tip{}, fmt.Errorf("%w: чаевые к заказу %d: %v", ErrPaymentProvider, orderID, err)
+	}
+	switch res.Status {
+	case payment.Authorized:
+	case payment.Pending:
+		// Вебхуки приходят только по платежам заказов: чаевые, которые банк не подтвердил
+		// сразу, не ждем, а просим повторить
+		fail("банк не подтвердил платеж сразу")
+		return repo.Tip{}, fmt.Errorf("%w: банк не подтвердил платеж сразу, попробуйте другую карту", payment.ErrDeclined)
+	default:
+		fail(res.Reason)
+		return repo.Tip{}, fmt.Errorf("%w: %s", payment.ErrDeclined, res.Reason)
+	}
+
+	// Заказ уже доставлен, держать блокировку незачем — списываем сразу
+	key := fmt.Sprintf("tip-capture-%d-%d", t.ID, t.Attempt)
+	if err := s.provider.Capture(ctx, res.ProviderID, amount, key); err != nil {
+		if rerr := s.provider.Refund(ctx, res.ProviderID, amount, fmt.Sprintf("tip-refund-%d-%d", t.ID, t.Attempt)); rerr != nil {
+			log.Printf("Чаевые %d: не удалось снять блокировку после ошибки списания: %v", t.ID, rerr)
+		}
+		fail(err.Error())
+		return repo.Tip{}, fmt.Errorf("%w: чаевые к заказу %d: %v", ErrPaymentProvider, orderID, err)
+	}
+	if err := s.repo.UpdateTip(ctx, t.ID, repo.TipPaid, res.ProviderID, ""); err != nil {
+		return repo.Tip{}, err
+	}
+	return s.repo.GetTip(ctx, userID, orderID)
+}
+
+func (s *paymentService) GetTip(ctx context.Context, userID, orderID int64) (repo.Tip, error) {
+	return s.repo.GetTip(ctx, userID, orderID)
+}
+
+func (s *paymentService) SettlePayments(ctx context.Context) error {
+	settlements, err := s.repo.PaymentsToSettle(ctx, settleBatch)
+	if err != nil {
+		return err
+	}
+	for _, st := range settlements {
+		p := st.Payment
+		to := payment.Refunded
+		settle := s.provider.Refund
+		if st.Action == repo.SettleCapture {
+			to, settle = payment.Captured, s.provider.Capture
+		}
+		// Ключ по платежу и действию: повтор после сбоя записи в базу не спишет дважды
+		key := fmt.Sprintf("%s-%d-%d", st.Action, p.ID, p.Attempt)
+		update := repo.PaymentUpdate{Status: to, Amount: st.Amount}
+		switch err := settle(ctx, p.ProviderID, st.Amount, key); {
+		case errors.Is(err, payment.ErrNotFound):
+			// Повтор не поможет: платеж выпадает из PaymentsToSettle, причина остается в last_error
+			log.Printf("Платеж %d: провайдер не знает платеж, %s невозможен: %v", p.ID, st.Action, err)
+			update = repo.PaymentUpdate{Status: payment.Failed, Error: err.Error()}
+		case err != nil:
+			// Попробуем на следующем тике
+			log.Printf("Платеж %d: не удалось выполнить %s: %v", p.ID, st.Action, err)
+			update = repo.PaymentUpdate{Status: p.Status, Error: err.Error()}
+		}
+		if err := s.repo.UpdatePayment(ctx, p.ID, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/review"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+)
+
+// ReviewPage — страница отзывов; NextCursor передается в следующий запрос как cursor
+type ReviewPage struct {
+	Reviews    []review.Review `json:"reviews"`
+	NextCursor int64           `json:"next_cursor,omitempty"`
+}
+
+type ReviewService interface {
+	// Create — отзыв клиента на свой доставленный заказ: оценка еды, курьера или обоих
+	Create(ctx context.Context, userID, orderID int64, rv review.Review) (review.Review, error)
+	// Get — отзыв на заказ; только автору
+	Get(ctx context.Context, userID, orderID int64) (review.Review, error)
+	ProductRating(ctx context.Context, productID int64) (review.Summary, error)
+	CourierRating(ctx context.Context, courierID int64) (review.Summary, error)
+	// ProductReviews — опубликованные отзывы о еде в заказах с этим товаром
+	ProductReviews(ctx context.Context, productID, before int64, limit int) (ReviewPage, error)
+
+	// List — отзывы для модерации
+	List(ctx context.Context, f review.Filter) (ReviewPage, error)
+	// Hide убирает отзыв из публичных списков и рейтингов; причина обязательна
+	Hide(ctx context.Context, id int64, reason string) (review.Review, error)
+	// Restore возвращает скрытый отзыв
+	Restore(ctx context.Context, id int64) (review.Review, error)
+}
+
+type reviewService struct {
+	repo repo.Repository
+}
+
+func NewReviews(r repo.Repository) ReviewService {
+	return &reviewService{repo: r}
+}
+
+func (s *reviewService) Create(ctx context.Context, userID, orderID int64, rv review.Review) (review.Review, error) {
+	if err := review.Validate(&rv); err != nil {
+		return review.Review{}, err
+	}
+	rv.OrderID, rv.UserID = orderID, userID
+	return s.repo.CreateReview(ctx, rv)
+}
+
+func (s *reviewService) Get(ctx context.Context, userID, orderID int64) (review.Review, error) {
+	rv, err := s.repo.GetReview(ctx, orderID)
+	if err != nil {
+		return review.Review{}, err
+	}
+	if rv.UserID != userID {
+		return review.Review{}, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	return rv, nil
+}
+
+func (s *reviewService) ProductRating(ctx context.Context, productID int64) (review.Summary, error) {
+	return s.repo.ProductRating(ctx, productID)
+}
+
+func (s *reviewService) CourierRating(ctx context.Context, courierID int64) (review.Summary, error) {
+	return s.repo.CourierRating(ctx, courierID)
+}
+
+func (s *reviewService) ProductReviews(ctx context.Context, productID, before int64, limit int) (ReviewPage, error) {
+	return reviewPage(limit, func(limit int) ([]review.Review, error) {
+		reviews, err := s.repo.ProductReviews(ctx, productID, before, limit)
+		// Кто оставил отзыв, в публичный список не отдаем
+		for i := range reviews {
+			reviews[i].UserID = 0
+		}
+		return reviews, err
+	})
+}
+
+func (s *reviewService) List(ctx context.Context, f review.Filter) (ReviewPage, error) {
+	return reviewPage(f.Limit, func(limit int) ([]review.Review, error) {
+		f.Limit = limit
+		return s.repo.ListReviews(ctx, f)
+	})
+}
+
+func (s *reviewService) Hide(ctx context.Context, id int64, reason string) (review.Review, error) {
+	reason, err := review.ValidateReason(reason)
+	if err != nil {
+		return review.Review{}, err
+	}
+	return s.repo.SetReviewHidden(ctx, id, true, reason)
+}
+
+func (s *reviewService) Restore(ctx context.Context, id int64) (review.Review, error) {
+	return s.repo.SetReviewHidden(ctx, id, false, "")
+}
+
+// reviewPage читает на один отзыв больше страницы, как ListOrders: так понятно, есть ли следующая
+func reviewPage(limit int, load func(limit int) ([]review.Review, error)) (ReviewPage, error) {
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return ReviewPage{}, ErrInvalidLimit
+	}
+	reviews, err := load(limit + 1)
+	if err != nil {
+		return ReviewPage{}, err
+	}
+	page := ReviewPage{Reviews: reviews}
+	if len(reviews) > limit {
+		page.Reviews = reviews[:limit]
+		page.NextCursor = page.Reviews[limit-1].ID
+	}
+	if page.Reviews == nil {
+		page.Reviews = []review.Review{}
+	}
+	return page, nil
+}
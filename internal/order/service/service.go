@@ -2,33 +2,552 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/JuniorCrafter/fooddelivery/internal/catalog/schedule"
+	"github.com/JuniorCrafter/fooddelivery/internal/geo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/delivery"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/promo"
 	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+	"github.com/JuniorCrafter/fooddelivery/internal/order/status"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
 )
 
+var (
+	// ErrInvalidOptions — выбор опций не соответствует правилам групп товара
+	ErrInvalidOptions = errors.New("некорректный выбор опций")
+	// ErrKitchenClosed — кухня сейчас не работает или позиция сейчас не подается
+	ErrKitchenClosed     = errors.New("товар сейчас нельзя заказать: кухня закрыта")
+	ErrEmptyOrder        = errors.New("в заказе нет товаров")
+	ErrInvalidQty        = errors.New("количество товара должно быть больше нуля")
+	ErrInvalidLimit      = fmt.Errorf("limit должен быть от 1 до %d", maxPageSize)
+	ErrNoReason          = errors.New("укажите причину отмены")
+	ErrReasonTooLong     = fmt.Errorf("причина длиннее %d символов", maxReasonLen)
+	ErrNoDeliveryAddress = errors.New("укажите адрес доставки и его координаты")
+	// ErrInvalidIdempotencyKey — ключ пустой, длиннее 255 символов или не из печатных ASCII
+	ErrInvalidIdempotencyKey = errors.New("некорректный Idempotency-Key")
+	ErrInvalidTip            = fmt.Errorf("чаевые должны быть от 0 до %s", maxTip)
+)
+
+// maxTip — больше за один заказ не принимаем: скорее всего, лишний ноль
+var maxTip = money.FromMajor(5000)
+
+const maxReasonLen = 500
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// defaultTimezone — окно подачи товаров без кухни считаем по Москве, как и каталог
+const defaultTimezone = "Europe/Moscow"
+
+// OrderRequest — новый заказ, как его прислал клиент
+type OrderRequest struct {
+	UserID    int64
+	Items     []repo.OrderItem
+	PromoCode string // пусто — без скидки
+	// Delivery — адрес и координаты клиента; расстояние и цену доставки посчитает сервис
+	Delivery *repo.Delivery
+	// DeliverAt — начало слота доставки для заказа ко времени; nil — доставить как можно скорее
+	DeliverAt *time.Time
+	// Tip — чаевые курьеру; блокируются вместе с заказом, списываются только после доставки
+	Tip money.Money
+	// IdempotencyKey делает запрос повторяемым: повтор с тем же ключом и телом
+	// вернет тот же заказ, а не создаст второй
+	IdempotencyKey string
+}
+
 type Service interface {
-	PlaceOrder(ctx context.Context, userID int64, items []repo.OrderItem) (int64, error)
+	// PlaceOrder создает заказ; replayed = true, если это повтор по IdempotencyKey
+	PlaceOrder(ctx context.Context, req OrderRequest) (id int64, replayed bool, err error)
+	// Quote считает заказ, не создавая его: сумма еды, скидка, доставка и итог
+	Quote(ctx context.Context, req OrderRequest) (Quote, error)
+	// CancelOrder — отмена клиентом (только своего заказа) или администратором, причина обязательна.
+	// До начала готовки отмена бесплатная, после того как курьер забрал заказ — невозможна
+	CancelOrder(ctx context.Context, orderID int64, actor status.Actor, reason string) (repo.Cancellation, error)
+	// ChangeStatus — смена статуса рестораном (готовится, готов) или другой ролью по правилам status
+	ChangeStatus(ctx context.Context, orderID int64, to status.Status, actor status.Actor, reason string) error
+	// StatusHistory отдает историю владельцу заказа, назначенному курьеру и администратору;
+	// остальным заказ выглядит как несуществующий
+	StatusHistory(ctx context.Context, orderID int64, viewer status.Actor) ([]status.Change, error)
+	// GetOrder отдает заказ только его владельцу; чужой заказ выглядит как несуществующий
+	GetOrder(ctx context.Context, userID, orderID int64) (repo.Order, error)
+	ListOrders(ctx context.Context, f repo.ListFilter) (OrderPage, error)
+	// ExpireReservations отменяет заказы, не оплаченные за время резерва, и возвращает порции на склад
+	ExpireReservations(ctx context.Context) error
+	// ReleaseScheduledOrders отдает кухне заказы ко времени, у которых подошло время готовки
+	ReleaseScheduledOrders(ctx context.Context) error
+	// ExpireIdempotencyKeys удаляет ключи идемпотентности старше окна повтора
+	ExpireIdempotencyKeys(ctx context.Context) error
+
+	CreatePromoCode(ctx context.Context, c promo.Code) (promo.Code, error)
+	ListPromoCodes(ctx context.Context) ([]promo.Code, error)
+	// DeactivatePromoCode выключает код; уже оформленные с ним заказы не меняются
+	DeactivatePromoCode(ctx context.Context, code string) error
+}
+
+// StockChanged вызывается после изменения остатков: каталог показывает stock и sold_out
+// из своего кэша, поэтому его нужно сбросить. nil — сбрасывать нечего.
+type StockChanged func(ctx context.Context) error
+
+// Quote — сколько будет стоить заказ, если оформить его сейчас
+type Quote struct {
+	Subtotal money.Money   `json:"subtotal"` // еда с опциями
+	Discount money.Money   `json:"discount"`
+	Delivery repo.Delivery `json:"delivery"`
+	Total    money.Money   `json:"total"`
+	Tip      money.Money   `json:"tip"`
+	ToPay    money.Money   `json:"to_pay"` // Total вместе с чаевыми — столько заблокируем на карте
+}
+
+// OrderPage — страница заказов. NextCursor передается в следующий запрос как cursor,
+// 0 — страниц больше нет
+type OrderPage struct {
+	Orders     []repo.Order `json:"orders"`
+	NextCursor int64        `json:"next_cursor,omitempty"`
 }
 
 type orderService struct {
-	repo repo.Repository
+	repo           repo.Repository
+	reservationTTL time.Duration
+	idempotencyTTL time.Duration
+	pricing        delivery.Pricing
+	slots          SlotPolicy
+	stockChanged   StockChanged
 }
 
-func New(r repo.Repository) Service {
-	return &orderService{repo: r}
+func New(r repo.Repository, reservationTTL, idempotencyTTL time.Duration, pricing delivery.Pricing, slots SlotPolicy, stockChanged StockChanged) Service {
+	return &orderService{
+		repo:           r,
+		reservationTTL: reservationTTL,
+		idempotencyTTL: idempotencyTTL,
+		pricing:        pricing,
+		slots:          slots,
+		stockChanged:   stockChanged,
+	}
 }
 
-func (s *orderService) PlaceOrder(ctx context.Context, userID int64, items []repo.OrderItem) (int64, error) {
-	var total float64
-	for _, item := range items {
-		total += item.Price * float64(item.Quantity)
+func (s *orderService) PlaceOrder(ctx context.Context, req OrderRequest) (int64, bool, error) {
+	if len(req.Items) == 0 {
+		return 0, false, ErrEmptyOrder
+	}
+
+	// Повтор уже выполненного запроса отдаем сразу: с тех пор могли закрыться кухня или кончиться товар
+	var idem *repo.Idempotency
+	if req.IdempotencyKey != "" {
+		if !validIdempotencyKey(req.IdempotencyKey) {
+			return 0, false, ErrInvalidIdempotencyKey
+		}
+		idem = &repo.Idempotency{Key: req.IdempotencyKey, Hash: requestHash(req), TTL: s.idempotencyTTL}
+		id, err := s.repo.FindIdempotentOrder(ctx, req.UserID, *idem)
+		if err != nil || id != 0 {
+			return id, id != 0, err
+		}
+	}
+
+	order, products, err := s.price(ctx, req)
+	if err != nil {
+		return 0, false, err
+	}
+	id, replayed, err := s.repo.CreateOrder(ctx, order, idem)
+	if err != nil || replayed {
+		return id, replayed, err
+	}
+	for _, item := range order.Items {
+		if products[item.ProductID].LimitedStock {
+			s.notifyStockChanged(ctx)
+			break
+		}
+	}
+	return id, false, nil
+}
+
+func (s *orderService) Quote(ctx context.Context, req OrderRequest) (Quote, error) {
+	if len(req.Items) == 0 {
+		return Quote{}, ErrEmptyOrder
+	}
+	order, _, err := s.price(ctx, req)
+	if err != nil {
+		return Quote{}, err
+	}
+	return Quote{
+		Subtotal: order.TotalPrice.Add(order.Discount).Sub(order.Delivery.Fee),
+		Discount: order.Discount,
+		Delivery: *order.Delivery,
+		Total:    order.TotalPrice,
+		Tip:      order.Tip,
+		ToPay:    order.TotalPrice.Add(order.Tip),
+	}, nil
+}
+
+// price собирает заказ из запроса: цены и опции из каталога, скидка по промокоду и доставка.
+// Кухня должна работать, а лимиты промокода и остатки проверит уже транзакция создания заказа.
+func (s *orderService) price(ctx context.Context, req OrderRequest) (repo.Order, map[int64]repo.CatalogProduct, error) {
+	items := req.Items
+	if req.Delivery == nil || strings.TrimSpace(req.Delivery.Address) == "" ||
+		!(geo.Point{Lat: req.Delivery.Lat, Lon: req.Delivery.Lon}).Valid() {
+		return repo.Order{}, nil, ErrNoDeliveryAddress
+	}
+	tip, err := validateTip(req.Tip, false)
+	if err != nil {
+		return repo.Order{}, nil, err
+	}
+	now := time.Now()
+	slot, err := s.slots.slot(req.DeliverAt, now)
+	if err != nil {
+		return repo.Order{}, nil, err
+	}
+	// Заказ ко времени кухня начнет готовить в ReleaseAt — тогда она и должна работать
+	cookAt := now
+	if slot != nil {
+		cookAt = slot.ReleaseAt
+	}
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		if item.Quantity <= 0 {
+			return repo.Order{}, nil, fmt.Errorf("%w (товар %d)", ErrInvalidQty, item.ProductID)
+		}
+		ids[i] = item.ProductID
+	}
+	products, err := s.repo.GetCatalogProducts(ctx, ids)
+	if err != nil {
+		return repo.Order{}, nil, err
+	}
+	if err := resolvePrices(items, products); err != nil {
+		return repo.Order{}, nil, err
+	}
+	if err := checkOpen(items, products, cookAt); err != nil {
+		return repo.Order{}, nil, err
+	}
+
+	total := money.Zero()
+	lines := make([]promo.Line, len(items))
+	for i := range items {
+		if err := applyOptions(ctx, s.repo, &items[i]); err != nil {
+			return repo.Order{}, nil, err
+		}
+		lines[i] = promo.Line{
+			ProductID:  items[i].ProductID,
+			CategoryID: products[items[i].ProductID].CategoryID,
+			Amount:     items[i].Price.Mul(items[i].Quantity),
+		}
+		items[i].Discount = money.Zero()
+		items[i].TaxCategory = products[items[i].ProductID].TaxCategory
+		total = total.Add(lines[i].Amount)
 	}
 
 	order := repo.Order{
-		UserID:     userID,
+		UserID:     req.UserID,
 		TotalPrice: total,
+		Discount:   money.Zero(),
+		Tip:        tip,
 		Items:      items,
+		Slot:       slot,
+	}
+	// Правила кода проверяем здесь, а лимиты использований — в транзакции создания заказа
+	if req.PromoCode != "" {
+		code, err := s.repo.GetPromoCode(ctx, req.PromoCode)
+		if err != nil {
+			return repo.Order{}, nil, err
+		}
+		discount, err := code.Discount(lines, now)
+		if err != nil {
+			return repo.Order{}, nil, err
+		}
+		order.Promo = &code
+		order.Discount = discount
+		for i, share := range code.Allocate(lines, discount) {
+			items[i].Discount = share
+		}
+		order.TotalPrice = total.Sub(discount)
+	}
+
+	// Доставка считается от самой дальней кухни заказа; пороги — по сумме еды со скидкой
+	dest := geo.Point{Lat: req.Delivery.Lat, Lon: req.Delivery.Lon}
+	var distance float64
+	for _, item := range items {
+		if loc := products[item.ProductID].KitchenLocation; loc != nil {
+			distance = max(distance, geo.Distance(*loc, dest))
+		}
+	}
+	quote, err := s.pricing.Quote(distance, order.TotalPrice)
+	if err != nil {
+		return repo.Order{}, nil, err
+	}
+	order.Delivery = &repo.Delivery{
+		Address:    strings.TrimSpace(req.Delivery.Address),
+		Lat:        dest.Lat,
+		Lon:        dest.Lon,
+		DistanceKm: quote.DistanceKm,
+		Fee:        quote.Fee,
+	}
+	order.TotalPrice = order.TotalPrice.Add(quote.Fee)
+	return order, products, nil
+}
+
+func (s *orderService) CancelOrder(ctx context.Context, orderID int64, actor status.Actor, reason string) (repo.Cancellation, error) {
+	reason, err := validateReason(reason)
+	if err != nil {
+		return repo.Cancellation{}, err
+	}
+	c, released, err := s.repo.CancelOrder(ctx, orderID, actor, reason)
+	if err != nil {
+		return repo.Cancellation{}, err
+	}
+	if released > 0 {
+		s.notifyStockChanged(ctx)
+	}
+	return c, nil
+}
+
+func (s *orderService) ChangeStatus(ctx context.Context, orderID int64, to status.Status, actor status.Actor, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if to == status.Cancelled {
+		var err error
+		if reason, err = validateReason(reason); err != nil {
+			return err
+		}
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLen {
+		return ErrReasonTooLong
+	}
+	released, err := s.repo.ChangeStatus(ctx, orderID, to, actor, reason)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		s.notifyStockChanged(ctx)
+	}
+	return nil
+}
+
+func (s *orderService) StatusHistory(ctx context.Context, orderID int64, viewer status.Actor) ([]status.Change, error) {
+	if viewer.Type != status.ActorAdmin {
+		userID, courierUserID, err := s.repo.OrderParties(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		owner := viewer.Type == status.ActorClient && viewer.ID == userID
+		courier := viewer.Type == status.ActorCourier && courierUserID != 0 && viewer.ID == courierUserID
+		if !owner && !courier {
+			return nil, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+		}
+	}
+	return s.repo.GetStatusHistory(ctx, orderID)
+}
+
+func (s *orderService) GetOrder(ctx context.Context, userID, orderID int64) (repo.Order, error) {
+	o, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return repo.Order{}, err
+	}
+	if o.UserID != userID {
+		return repo.Order{}, fmt.Errorf("%w: %d", status.ErrOrderNotFound, orderID)
+	}
+	return o, nil
+}
+
+func (s *orderService) ListOrders(ctx context.Context, f repo.ListFilter) (OrderPage, error) {
+	if f.Limit == 0 {
+		f.Limit = defaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > maxPageSize {
+		return OrderPage{}, ErrInvalidLimit
+	}
+
+	// Берем на один заказ больше: так без COUNT понятно, есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	orders, err := s.repo.ListOrders(ctx, f)
+	if err != nil {
+		return OrderPage{}, err
+	}
+	page := OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		page.NextCursor = page.Orders[limit-1].ID
+	}
+	if page.Orders == nil {
+		page.Orders = []repo.Order{}
+	}
+	return page, nil
+}
+
+func (s *orderService) CreatePromoCode(ctx context.Context, c promo.Code) (promo.Code, error) {
+	if err := promo.Validate(&c); err != nil {
+		return promo.Code{}, err
+	}
+	c.IsActive = true
+	c.UsedCount = 0
+	id, err := s.repo.CreatePromoCode(ctx, c)
+	if err != nil {
+		return promo.Code{}, err
 	}
+	c.ID = id
+	return c, nil
+}
+
+func (s *orderService) ListPromoCodes(ctx context.Context) ([]promo.Code, error) {
+	return s.repo.ListPromoCodes(ctx)
+}
+
+func (s *orderService) DeactivatePromoCode(ctx context.Context, code string) error {
+	return s.repo.DeactivatePromoCode(ctx, code)
+}
 
-	return s.repo.CreateOrder(ctx, order)
+func (s *orderService) ExpireIdempotencyKeys(ctx context.Context) error {
+	_, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, s.idempotencyTTL)
+	return err
+}
+
+func (s *orderService) ExpireReservations(ctx context.Context) error {
+	cancelled, released, err := s.repo.ExpireOrders(ctx, s.reservationTTL)
+	if err != nil {
+		return err
+	}
+	if cancelled > 0 {
+		log.Printf("Отменено %d неоплаченных заказов, снято резервов: %d", cancelled, released)
+	}
+	if released > 0 {
+		s.notifyStockChanged(ctx)
+	}
+	return nil
+}
+
+// validateReason — причина отмены обязательна, она попадает в историю и в событие order.cancelled
+func validateReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", ErrNoReason
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLen {
+		return "", ErrReasonTooLong
+	}
+	return reason, nil
+}
+
+// notifyStockChanged не возвращает ошибку: заказ уже сохранен, а устаревший кэш каталога истечет по TTL
+func (s *orderService) notifyStockChanged(ctx context.Context) {
+	if s.stockChanged == nil {
+		return
+	}
+	if err := s.stockChanged(ctx); err != nil {
+		log.Printf("Не удалось сбросить кэш каталога после изменения остатков: %v", err)
+	}
+}
+
+// resolvePrices подставляет в позиции текущие цены каталога. Цена от клиента не используется:
+// иначе пиццу можно было бы заказать за рубль. Надбавки опций добавит applyOptions.
+func resolvePrices(items []repo.OrderItem, products map[int64]repo.CatalogProduct) error {
+	for i, item := range items {
+		p, ok := products[item.ProductID]
+		if !ok {
+			return fmt.Errorf("%w: %d", repo.ErrProductNotFound, item.ProductID)
+		}
+		if !p.IsAvailable {
+			return fmt.Errorf("%w: %d", repo.ErrProductUnavailable, item.ProductID)
+		}
+		items[i].Price = p.Price
+		items[i].BasePrice = p.Price
+	}
+	return nil
+}
+
+// checkOpen не дает заказать из закрытой кухни или позицию вне её окна подачи (завтрак в 15:00)
+func checkOpen(items []repo.OrderItem, products map[int64]repo.CatalogProduct, now time.Time) error {
+	for _, item := range items {
+		ps := products[item.ProductID]
+		timezone := defaultTimezone
+		if ps.KitchenID != 0 {
+			open, err := ps.Kitchen.IsOpen(now)
+			if err != nil {
+				return err
+			}
+			if !open {
+				return fmt.Errorf("%w (товар %d)", ErrKitchenClosed, item.ProductID)
+			}
+			timezone = ps.Kitchen.Timezone
+		}
+		inWindow, err := schedule.InWindow(ps.AvailableFrom, ps.AvailableUntil, timezone, now)
+		if err != nil {
+			return err
+		}
+		if !inWindow {
+			return fmt.Errorf("%w: товар %d подается с %s до %s", ErrKitchenClosed, item.ProductID, ps.AvailableFrom, ps.AvailableUntil)
+		}
+	}
+	return nil
+}
+
+// applyOptions проверяет выбранные опции по правилам каталога и
+// пересчитывает цену позиции: к цене товара прибавляются надбавки опций.
+// Названия и надбавки берутся из базы, а не из запроса клиента.
+func applyOptions(ctx context.Context, r repo.Repository, item *repo.OrderItem) error {
+	groups, err := r.GetOptionGroups(ctx, item.ProductID)
+	if err != nil {
+		return err
+	}
+
+	// Индекс: id опции -> (группа, опция)
+	type ref struct {
+		group  int
+		option repo.ItemOption
+	}
+	known := make(map[int64]ref)
+	for gi, g := range groups {
+		for _, o := range g.Options {
+			known[o.OptionID] = ref{group: gi, option: o}
+		}
+	}
+
+	selected := make([]int, len(groups))
+	seen := make(map[int64]bool)
+	resolved := make([]repo.ItemOption, 0, len(item.Options))
+	for _, chosen := range item.Options {
+		ref, ok := known[chosen.OptionID]
+		if !ok {
+			return fmt.Errorf("%w: опции %d нет у товара %d", ErrInvalidOptions, chosen.OptionID, item.ProductID)
+		}
+		if seen[chosen.OptionID] {
+			return fmt.Errorf("%w: опция %d выбрана дважды", ErrInvalidOptions, chosen.OptionID)
+		}
+		seen[chosen.OptionID] = true
+		selected[ref.group]++
+		resolved = append(resolved, ref.option)
+		item.Price = item.Price.Add(ref.option.PriceDelta)
+	}
+
+	for gi, g := range groups {
+		if selected[gi] < g.MinSelect || selected[gi] > g.MaxSelect {
+			return fmt.Errorf("%w: в группе %q нужно выбрать от %d до %d", ErrInvalidOptions, g.Name, g.MinSelect, g.MaxSelect)
+		}
+	}
+	if !item.Price.IsPositive() {
+		return fmt.Errorf("%w: итоговая цена товара %d должна быть больше нуля", ErrInvalidOptions, item.ProductID)
+	}
+
+	item.Options = resolved
+	return nil
+}
+
+// validateTip проверяет сумму чаевых; required — чаевые после доставки, ноль там бессмыслен
+func validateTip(tip money.Money, required bool) (money.Money, error) {
+	if tip.IsZero() && !required {
+		return money.Zero(), nil
+	}
+	// Заказ считается в рублях: чаевые в другой валюте не сложить с ним
+	if tip.Currency != "" && tip.Currency != money.DefaultCurrency {
+		return money.Money{}, ErrInvalidTip
+	}
+	if !tip.IsPositive() || tip.Cmp(maxTip) > 0 {
+		return money.Money{}, ErrInvalidTip
+	}
+	return tip, nil
 }
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/repo"
+)
+
+// ErrInvalidSlot — время доставки не совпадает с началом слота, слишком близко или слишком далеко
+var ErrInvalidSlot = errors.New("некорректное время доставки")
+
+// SlotPolicy — правила заказов ко времени
+type SlotPolicy struct {
+	Length   time.Duration // длина слота доставки; слоты начинаются с полуночи UTC: 12:00, 12:30, ...
+	Capacity int           // сколько заказов принимает один слот
+	Lead     time.Duration // за сколько до слота заказ уходит на кухню
+	Horizon  time.Duration // на сколько вперед можно заказать
+}
+
+// slot проверяет запрошенное время доставки. nil — заказ "как можно скорее".
+func (p SlotPolicy) slot(deliverAt *time.Time, now time.Time) (*repo.Slot, error) {
+	if deliverAt == nil {
+		return nil, nil
+	}
+	at := deliverAt.UTC()
+	if !at.Equal(at.Truncate(p.Length)) {
+		return nil, fmt.Errorf("%w: слоты начинаются каждые %s", ErrInvalidSlot, p.Length)
+	}
+	if at.Before(now.Add(p.Lead)) {
+		return nil, fmt.Errorf("%w: ко времени можно заказать не раньше чем через %s", ErrInvalidSlot, p.Lead)
+	}
+	if at.After(now.Add(p.Horizon)) {
+		return nil, fmt.Errorf("%w: заказать можно не дальше чем на %s вперед", ErrInvalidSlot, p.Horizon)
+	}
+	return &repo.Slot{DeliverAt: at, ReleaseAt: at.Add(-p.Lead), Capacity: p.Capacity}, nil
+}
+
+func (s *orderService) ReleaseScheduledOrders(ctx context.Context) error {
+	released, err := s.repo.ReleaseScheduled(ctx)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		log.Printf("На кухню отправлено %d заказов ко времени", released)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSlotPolicySlot(t *testing.T) {
+	p := SlotPolicy{Length: 30 * time.Minute, Capacity: 20, Lead: 45 * time.Minute, Horizon: 7 * 24 * time.Hour}
+	now := time.Date(2026, 10, 16, 12, 10, 0, 0, time.UTC)
+	at := func(value string) *time.Time {
+		tm, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &tm
+	}
+
+	if s, err := p.slot(nil, now); s != nil || err != nil {
+		t.Fatalf("без времени доставки = %+v, %v, ожидали заказ как можно скорее", s, err)
+	}
+
+	tests := []struct {
+		name    string
+		at      *time.Time
+		ok      bool
+		release string
+	}{
+		{"ближайший допустимый слот", at("2026-10-16T13:00:00Z"), true, "2026-10-16T12:15:00Z"},
+		{"время в другом поясе приводится к UTC", at("2026-10-16T16:30:00+03:00"), true, "2026-10-16T12:45:00Z"},
+		{"не начало слота", at("2026-10-16T14:10:00Z"), false, ""},
+		{"слишком близко", at("2026-10-16T12:30:00Z"), false, ""},
+		{"ровно на горизонте", at("2026-10-23T12:00:00Z"), true, "2026-10-23T11:15:00Z"},
+		{"за горизонтом", at("2026-10-23T12:30:00Z"), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := p.slot(tt.at, now)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidSlot) {
+					t.Fatalf("slot() = %+v, %v, ожидали ErrInvalidSlot", s, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !s.DeliverAt.Equal(*tt.at) || s.DeliverAt.Location() != time.UTC {
+				t.Fatalf("DeliverAt = %v", s.DeliverAt)
+			}
+			if want := *at(tt.release); !s.ReleaseAt.Equal(want) {
+				t.Fatalf("ReleaseAt = %v, ожидали %v", s.ReleaseAt, want)
+			}
+			if s.Capacity != p.Capacity {
+				t.Fatalf("Capacity = %d", s.Capacity)
+			}
+		})
+	}
+
+	// Слот, который начинается ровно через Lead, еще можно заказать
+	edge := now.Add(50 * time.Minute) // 13:00
+	p.Lead = 50 * time.Minute
+	if _, err := p.slot(&edge, now); err != nil {
+		t.Fatalf("слот ровно через Lead: %v", err)
+	}
+}
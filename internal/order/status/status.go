@@ -0,0 +1,189 @@
+// Package status — единая машина состояний заказа. Все сервисы, которые меняют
+// статус (заказы, курьеры), делают это только через Apply: он проверяет переход,
+// обновляет orders.status, пишет запись в order_status_history и событие
+// order.status_changed в outbox одной транзакцией.
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/order/events"
+	"github.com/jackc/pgx/v5"
+)
+
+type Status string
+
+const (
+	AwaitingPayment Status = "awaiting_payment" // ждет, пока провайдер заблокирует сумму на карте
+	Scheduled       Status = "scheduled"        // заказ ко времени: спит, пока планировщик не отдаст его на кухню
+	New             Status = "new"
+	Accepted        Status = "accepted" // курьер взял заказ
+	Cooking         Status = "cooking"
+	Ready           Status = "ready" // готов и ждет курьера
+	Delivering      Status = "delivering"
+	Completed       Status = "completed"
+	Cancelled       Status = "cancelled" // отменен до того, как курьер забрал заказ; см. FreeCancel
+	Failed          Status = "failed"    // не удалось приготовить или доставить
+)
+
+var (
+	ErrUnknown           = errors.New("неизвестный статус заказа")
+	ErrIllegalTransition = errors.New("недопустимая смена статуса заказа")
+	ErrNotAllowed        = errors.New("эта роль не может ставить такой статус")
+	ErrOrderNotFound     = errors.New("заказ не найден")
+)
+
+// transitions — разрешенные переходы. Завершенные, отмененные и проваленные заказы конечны.
+var transitions = map[Status][]Status{
+	AwaitingPayment: {New, Scheduled, Cancelled},
+	Scheduled:       {New, Cancelled},
+	New:             {Accepted, Cancelled},
+	Accepted:        {Cooking, Cancelled, Failed},
+	Cooking:         {Ready, Cancelled, Failed},
+	Ready:           {Delivering, Cancelled, Failed},
+	Delivering:      {Completed, Failed},
+}
+
+// Кто меняет статус
+const (
+	ActorClient     = "client"
+	ActorCourier    = "courier"
+	ActorRestaurant = "restaurant"
+	ActorAdmin      = "admin"  // поддержка: может отменить любой заказ
+	ActorSystem     = "system" // фоновые задачи, например отмена по таймауту резерва
+)
+
+// allowed — какие статусы может ставить каждая роль; системе можно всё, что позволяет машина состояний.
+// В new заказ ко времени переводит только планировщик: иначе его отдали бы кухне раньше срока.
+var allowed = map[string][]Status{
+	ActorClient:     {Cancelled},
+	ActorCourier:    {Accepted, Delivering, Completed, Failed},
+	ActorRestaurant: {Cooking, Ready, Cancelled, Failed},
+	ActorAdmin:      {Cancelled},
+}
+
+// FreeCancel — отмена из статуса from бесплатна: готовить еще не начали,
+// деньги возвращаются полностью, порции — на склад. После начала готовки
+// отменить еще можно, но еда уже потрачена. После того как курьер забрал заказ, отмены нет.
+func FreeCancel(from Status) bool {
+	return from == AwaitingPayment || from == Scheduled || from == New || from == Accepted
+}
+
+// Actor — кто сделал переход; ID = 0 — неизвестен (например, токен без идентификатора)
+type Actor struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id,omitempty"`
+}
+
+// Change — запись истории статусов
+type Change struct {
+	From      Status    `json:"from,omitempty"` // пусто у создания заказа
+	To        Status    `json:"to"`
+	Actor     Actor     `json:"actor"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Parse проверяет строку из запроса
+func Parse(s string) (Status, error) {
+	switch st := Status(s); st {
+	case AwaitingPayment, Scheduled, New, Accepted, Cooking, Ready, Delivering, Completed, Cancelled, Failed:
+		return st, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknown, s)
+}
+
+// CanTransition — допустим ли переход from -> to
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func permitted(actor Actor, to Status) bool {
+	if actor.Type == ActorSystem {
+		return true
+	}
+	for _, st := range allowed[actor.Type] {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply переводит заказ в статус to внутри транзакции tx и возвращает прежний статус.
+// Строка заказа блокируется, поэтому два одновременных перехода не проскочат оба.
+// reason попадает в историю; пустой — без причины.
+func Apply(ctx context.Context, tx pgx.Tx, orderID int64, to Status, actor Actor, reason string) (Status, error) {
+	if !permitted(actor, to) {
+		return "", fmt.Errorf("%w: %s -> %s", ErrNotAllowed, actor.Type, to)
+	}
+	var from Status
+	err := tx.QueryRow(ctx, "SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&from)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %d", ErrOrderNotFound, orderID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !CanTransition(from, to) {
+		return from, fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from, to)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE orders SET status = $2 WHERE id = $1", orderID, to); err != nil {
+		return from, err
+	}
+	return from, Record(ctx, tx, orderID, from, to, actor, reason)
+}
+
+// Record пишет переход в историю и событие order.status_changed в outbox заказов.
+// Отдельно от Apply нужен при создании заказа (from = "").
+func Record(ctx context.Context, tx pgx.Tx, orderID int64, from, to Status, actor Actor, reason string) error {
+	var actorID *int64
+	if actor.ID != 0 {
+		actorID = &actor.ID
+	}
+	query := `
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor_type, actor_id, reason)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, ''))`
+	if _, err := tx.Exec(ctx, query, orderID, string(from), string(to), actor.Type, actorID, reason); err != nil {
+		return err
+	}
+
+	// Клиенту и курьеру нужно знать, кому слать уведомление
+	data := events.OrderStatusChangedData{
+		OrderID: orderID, From: string(from), To: string(to), Actor: events.Actor(actor), Reason: reason,
+	}
+	err := tx.QueryRow(ctx, "SELECT user_id, COALESCE(courier_id, 0) FROM orders WHERE id = $1", orderID).Scan(&data.UserID, &data.CourierID)
+	if err != nil {
+		return err
+	}
+	return events.Enqueue(ctx, tx, orderID, events.OrderStatusChanged, data)
+}
+
+// querier — пул или транзакция
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// History — все переходы заказа по времени
+func History(ctx context.Context, q querier, orderID int64) ([]Change, error) {
+	query := `
+		SELECT COALESCE(from_status, ''), to_status, actor_type, COALESCE(actor_id, 0), COALESCE(reason, ''), created_at
+		FROM order_status_history WHERE order_id = $1 ORDER BY id`
+	rows, err := q.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Change, error) {
+		var c Change
+		err := row.Scan(&c.From, &c.To, &c.Actor.Type, &c.Actor.ID, &c.Reason, &c.CreatedAt)
+		return c, err
+	})
+}
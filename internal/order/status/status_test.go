@@ -0,0 +1,98 @@
+package status
+
+import (
+	"errors"
+	"testing"
+)
+
+var all = []Status{AwaitingPayment, Scheduled, New, Accepted, Cooking, Ready, Delivering, Completed, Cancelled, Failed}
+
+func TestCanTransition(t *testing.T) {
+	// Полная таблица: все пары, которых здесь нет, запрещены
+	legal := map[[2]Status]bool{
+		{AwaitingPayment, New}:       true,
+		{AwaitingPayment, Scheduled}: true,
+		{AwaitingPayment, Cancelled}: true,
+		{Scheduled, New}:             true,
+		{Scheduled, Cancelled}:       true,
+		{New, Accepted}:              true,
+		{New, Cancelled}:             true,
+		{Accepted, Cooking}:          true,
+		{Accepted, Cancelled}:        true,
+		{Accepted, Failed}:           true,
+		{Cooking, Ready}:             true,
+		{Cooking, Cancelled}:         true,
+		{Cooking, Failed}:            true,
+		{Ready, Delivering}:          true,
+		{Ready, Cancelled}:           true,
+		{Ready, Failed}:              true,
+		{Delivering, Completed}:      true,
+		{Delivering, Failed}:         true,
+	}
+	for _, from := range all {
+		for _, to := range all {
+			if got, want := CanTransition(from, to), legal[[2]Status{from, to}]; got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, ожидали %v", from, to, got, want)
+			}
+		}
+	}
+	// Конечные статусы никуда не ведут, в том числе в самих себя
+	for _, final := range []Status{Completed, Cancelled, Failed} {
+		if len(transitions[final]) != 0 {
+			t.Errorf("из %s есть переходы: %v", final, transitions[final])
+		}
+	}
+}
+
+func TestPermitted(t *testing.T) {
+	tests := []struct {
+		actor string
+		to    []Status
+	}{
+		{ActorClient, []Status{Cancelled}},
+		{ActorCourier, []Status{Accepted, Delivering, Completed, Failed}},
+		{ActorRestaurant, []Status{Cooking, Ready, Cancelled, Failed}},
+		{ActorAdmin, []Status{Cancelled}},
+		{ActorSystem, all},
+		{"guest", nil},
+	}
+	for _, tt := range tests {
+		want := make(map[Status]bool)
+		for _, st := range tt.to {
+			want[st] = true
+		}
+		for _, to := range all {
+			if got := permitted(Actor{Type: tt.actor}, to); got != want[to] {
+				t.Errorf("permitted(%s, %s) = %v, ожидали %v", tt.actor, to, got, want[to])
+			}
+		}
+	}
+	// Оплату подтверждает только провайдер, а заказ ко времени на кухню отдает только планировщик
+	for _, actor := range []string{ActorClient, ActorCourier, ActorRestaurant, ActorAdmin} {
+		if permitted(Actor{Type: actor}, New) || permitted(Actor{Type: actor}, Scheduled) {
+			t.Errorf("%s не должен ставить new или scheduled", actor)
+		}
+	}
+}
+
+func TestFreeCancel(t *testing.T) {
+	free := map[Status]bool{AwaitingPayment: true, Scheduled: true, New: true, Accepted: true}
+	for _, st := range all {
+		if got := FreeCancel(st); got != free[st] {
+			t.Errorf("FreeCancel(%s) = %v, ожидали %v", st, got, free[st])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, st := range all {
+		if got, err := Parse(string(st)); err != nil || got != st {
+			t.Errorf("Parse(%q) = %q, %v", st, got, err)
+		}
+	}
+	for _, s := range []string{"", "New", "done"} {
+		if _, err := Parse(s); !errors.Is(err, ErrUnknown) {
+			t.Errorf("Parse(%q) = %v, ожидали ErrUnknown", s, err)
+		}
+	}
+}
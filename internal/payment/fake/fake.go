@@ -0,0 +1,157 @@
+// Package fake — платежный провайдер для разработки и тестов. Деньги никуда не уходят,
+// платежи живут в памяти процесса. Результат Authorize задается токеном карты:
+//
+//	tok_decline — банк отказал
+//	tok_pending — ответ придет вебхуком (его можно собрать через Webhook)
+//	любой другой — сумма заблокирована
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/payment"
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+const (
+	TokenDecline = "tok_decline"
+	TokenPending = "tok_pending"
+
+	// SignatureHeader — заголовок с подписью вебхука
+	SignatureHeader = "X-Fake-Signature"
+)
+
+type record struct {
+	status payment.Status
+	amount money.Money
+}
+
+type Provider struct {
+	secret string
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*record
+	keys     map[string]payment.Result // ключ идемпотентности Authorize -> ответ первого вызова
+	settled  map[string]bool           // ключи идемпотентности Capture и Refund
+}
+
+// New — fake-провайдер; secret подписывает вебхуки
+func New(secret string) *Provider {
+	return &Provider{
+		secret:   secret,
+		payments: make(map[string]*record),
+		keys:     make(map[string]payment.Result),
+		settled:  make(map[string]bool),
+	}
+}
+
+func (p *Provider) Name() string { return "fake" }
+
+func (p *Provider) Authorize(ctx context.Context, req payment.AuthorizeRequest) (payment.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prev, ok := p.keys[req.IdempotencyKey]; ok {
+		return prev, nil
+	}
+	if !req.Amount.IsPositive() {
+		return payment.Result{}, fmt.Errorf("fake: сумма должна быть больше нуля, получено %s", req.Amount)
+	}
+
+	p.seq++
+	res := payment.Result{ProviderID: fmt.Sprintf("fake_%d", p.seq), Status: payment.Authorized}
+	switch req.Token {
+	case TokenDecline:
+		res.Status, res.Reason = payment.Declined, "недостаточно средств"
+	case TokenPending:
+		res.Status = payment.Pending
+	}
+	p.payments[res.ProviderID] = &record{status: res.Status, amount: req.Amount}
+	if req.IdempotencyKey != "" {
+		p.keys[req.IdempotencyKey] = res
+	}
+	return res, nil
+}
+
+func (p *Provider) Capture(ctx context.Context, providerID string, amount money.Money, idempotencyKey string) error {
+	return p.settle(providerID, amount, idempotencyKey, payment.Captured)
+}
+
+func (p *Provider) Refund(ctx context.Context, providerID string, amount money.Money, idempotencyKey string) error {
+	return p.settle(providerID, amount, idempotencyKey, payment.Refunded)
+}
+
+func (p *Provider) settle(providerID string, amount money.Money, idempotencyKey string, to payment.Status) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.settled[idempotencyKey] {
+		return nil
+	}
+	rec, ok := p.payments[providerID]
+	if !ok {
+		return fmt.Errorf("fake: %w: %s", payment.ErrNotFound, providerID)
+	}
+	if !payment.CanTransition(rec.status, to) {
+		return fmt.Errorf("fake: %w: %s -> %s", payment.ErrIllegalTransition, rec.status, to)
+	}
+	if amount.Cmp(rec.amount) > 0 {
+		return fmt.Errorf("fake: сумма %s больше заблокированной %s", amount, rec.amount)
+	}
+	rec.status = to
+	if idempotencyKey != "" {
+		p.settled[idempotencyKey] = true
+	}
+	return nil
+}
+
+type webhookBody struct {
+	PaymentID string         `json:"payment_id"`
+	Status    payment.Status `json:"status"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+// Webhook переводит pending-платеж в status и собирает подписанный вебхук об этом —
+// так в разработке и тестах имитируется асинхронный ответ банка
+func (p *Provider) Webhook(providerID string, status payment.Status, reason string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rec, ok := p.payments[providerID]
+	if !ok {
+		return nil, nil, fmt.Errorf("fake: %w: %s", payment.ErrNotFound, providerID)
+	}
+	if !payment.CanTransition(rec.status, status) {
+		return nil, nil, fmt.Errorf("fake: %w: %s -> %s", payment.ErrIllegalTransition, rec.status, status)
+	}
+	rec.status = status
+
+	body, err := json.Marshal(webhookBody{PaymentID: providerID, Status: status, Reason: reason})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(SignatureHeader, payment.Sign(p.secret, body))
+	return body, header, nil
+}
+
+func (p *Provider) ParseWebhook(body []byte, header http.Header) (payment.Result, error) {
+	if !payment.VerifySignature(p.secret, body, header.Get(SignatureHeader)) {
+		return payment.Result{}, payment.ErrInvalidSignature
+	}
+	var wb webhookBody
+	if err := json.Unmarshal(body, &wb); err != nil {
+		return payment.Result{}, fmt.Errorf("%w: %v", payment.ErrInvalidWebhook, err)
+	}
+	if wb.PaymentID == "" {
+		return payment.Result{}, fmt.Errorf("%w: нет payment_id", payment.ErrInvalidWebhook)
+	}
+	switch wb.Status {
+	case payment.Authorized, payment.Declined, payment.Failed, payment.Captured, payment.Refunded:
+	default:
+		return payment.Result{}, fmt.Errorf("%w: статус %q", payment.ErrInvalidWebhook, wb.Status)
+	}
+	return payment.Result{ProviderID: wb.PaymentID, Status: wb.Status, Reason: wb.Reason}, nil
+}
@@ -0,0 +1,102 @@
+// Package payment — оплата заказов через платежного провайдера.
+//
+// Деньги списываются в два шага: при оформлении заказа сумма блокируется на карте
+// (Authorize), а списывается после доставки (Capture). Если заказ отменили или не
+// довезли, блокировка снимается (Refund). Провайдер может ответить на Authorize не сразу:
+// тогда платеж остается pending, а итог приходит вебхуком с подписью.
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+type Status string
+
+const (
+	Pending    Status = "pending"    // ждем ответа провайдера
+	Authorized Status = "authorized" // сумма заблокирована на карте
+	Declined   Status = "declined"   // банк отказал, можно попробовать другую карту
+	Failed     Status = "failed"     // провайдер не ответил или ответил ошибкой
+	Captured   Status = "captured"   // деньги списаны
+	Refunded   Status = "refunded"   // блокировка снята или деньги возвращены
+)
+
+var (
+	ErrInvalidSignature = errors.New("неверная подпись вебхука")
+	ErrInvalidWebhook   = errors.New("некорректный вебхук")
+	ErrDeclined         = errors.New("платеж отклонен")
+	ErrNotFound         = errors.New("платеж не найден")
+	// ErrIllegalTransition — платеж уже в другом конечном состоянии, например вебхук пришел повторно или не по порядку
+	ErrIllegalTransition = errors.New("недопустимая смена статуса платежа")
+)
+
+// transitions — как меняется статус платежа. Отклоненный и упавший платеж можно начать заново.
+// Заблокированный платеж падает, если провайдер его больше не знает: списывать и возвращать нечего.
+var transitions = map[Status][]Status{
+	Pending:    {Authorized, Declined, Failed},
+	Authorized: {Captured, Refunded, Failed},
+	Declined:   {Pending},
+	Failed:     {Pending},
+}
+
+// CanTransition — допустим ли переход from -> to
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeRequest — заблокировать сумму заказа на карте
+type AuthorizeRequest struct {
+	OrderID int64
+	Amount  money.Money
+	Token   string // токен карты от платежной формы провайдера; номер карты к нам не попадает
+	// IdempotencyKey — повтор с тем же ключом не заблокирует сумму второй раз
+	IdempotencyKey string
+}
+
+// Result — ответ провайдера на Authorize и событие из вебхука
+type Result struct {
+	ProviderID string // id платежа у провайдера
+	Status     Status
+	Reason     string // почему отказ
+}
+
+// Provider — платежный провайдер. Реализации: fake для разработки и тестов.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	// Capture списывает заблокированную сумму, amount — не больше заблокированной
+	Capture(ctx context.Context, providerID string, amount money.Money, idempotencyKey string) error
+	// Refund снимает блокировку или возвращает списанное
+	Refund(ctx context.Context, providerID string, amount money.Money, idempotencyKey string) error
+	// ParseWebhook проверяет подпись и разбирает уведомление о платеже
+	ParseWebhook(body []byte, header http.Header) (Result, error)
+}
+
+// Sign — HMAC-SHA256 тела вебхука в hex
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature сравнивает подпись за постоянное время, чтобы ее нельзя было подобрать по таймингу
+func VerifySignature(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return false
+	}
+	want, _ := hex.DecodeString(Sign(secret, body))
+	return hmac.Equal(got, want)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache — read-through кэш поверх Redis.
+// При промахе данные загружает функция load, причем одновременные промахи
+// по одному ключу схлопываются в один вызов (single-flight), чтобы толпа
+// запросов после инвалидации не положила базу.
+// loadTimeout ограничивает общую загрузку: она отвязана от контекста запроса, см. GetOrLoad
+const loadTimeout = 30 * time.Second
+
+type Cache struct {
+	rdb   *redis.Client
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+func New(rdb *redis.Client, ttl time.Duration) *Cache {
+	return &Cache{rdb: rdb, ttl: ttl}
+}
+
+// GetOrLoad отдает значение из Redis, а при промахе — из load, попутно сохраняя его.
+// Если Redis недоступен, просто ходим в load: кэш не должен ронять сервис.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		log.Printf("cache: ошибка чтения %s: %v", key, err)
+	}
+
+	// Загрузку ждут все одновременные запросы, поэтому она не должна оборваться вместе с первым из них:
+	// контекст отвязываем от отмены, но ограничиваем по времени. Сам вызывающий перестает ждать по своему ctx.
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		data, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.rdb.Set(loadCtx, key, data, c.ttl).Err(); err != nil {
+			log.Printf("cache: ошибка записи %s: %v", key, err)
+		}
+		return data, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Generation возвращает текущее "поколение" пространства ключей.
+// Ключи строятся с номером поколения, поэтому инвалидация — это один INCR,
+// а старые записи сами истекут по TTL.
+func (c *Cache) Generation(ctx context.Context, key string) (int64, error) {
+	gen, err := c.rdb.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// Invalidate переводит пространство ключей на новое поколение
+func (c *Cache) Invalidate(ctx context.Context, key string) error {
+	return c.rdb.Incr(ctx, key).Err()
+}
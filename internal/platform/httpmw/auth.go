@@ -1,6 +1,7 @@
 package httpmw
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -39,7 +40,54 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Кладем в контекст, кто пришел: ручкам нужно знать владельца заказа
+		ctx := r.Context()
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if id, ok := claims["user_id"].(float64); ok {
+				ctx = context.WithValue(ctx, userIDKey, int64(id))
+			}
+			if role, ok := claims["role"].(string); ok {
+				ctx = context.WithValue(ctx, roleKey, role)
+			}
+		}
+
 		// Если всё ок — пропускаем запрос дальше к "официанту"
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+type ctxKey int
+
+const (
+	userIDKey ctxKey = iota
+	roleKey
+)
+
+// UserID — id пользователя из токена; false, если запрос прошел без AuthMiddleware
+func UserID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userIDKey).(int64)
+	return id, ok
+}
+
+// Role — роль из токена (client, courier, restaurant, admin) или пустая строка
+func Role(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey).(string)
+	return role
+}
+
+// RequireRole пускает дальше только токены с одной из ролей, остальным — 403.
+// Ставится после AuthMiddleware
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := Role(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package locale выбирает язык ответа: параметр ?lang= или заголовок Accept-Language.
+package locale
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default — язык, на котором контент хранится в основных колонках таблиц
+const Default = "ru"
+
+var ErrInvalid = errors.New("некорректный код языка, ожидается например en или en-GB")
+
+// Normalize проверяет тег BCP 47 в упрощенном виде (язык и необязательные подтеги)
+// и приводит его к нижнему регистру: "en-GB" -> "en-gb"
+func Normalize(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	parts := strings.Split(tag, "-")
+	if len(parts[0]) < 2 || len(parts[0]) > 3 || !isAlnum(parts[0], false) {
+		return "", ErrInvalid
+	}
+	for _, sub := range parts[1:] {
+		if len(sub) < 2 || len(sub) > 8 || !isAlnum(sub, true) {
+			return "", ErrInvalid
+		}
+	}
+	return tag, nil
+}
+
+func isAlnum(s string, digits bool) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (!digits || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Base отбрасывает регион: "en-gb" -> "en"
+func Base(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+// FromRequest возвращает языки клиента по убыванию предпочтения.
+// Явный ?lang= важнее заголовка: так язык можно выбрать ссылкой.
+func FromRequest(r *http.Request) []string {
+	var tags []string
+	if lang, err := Normalize(r.URL.Query().Get("lang")); err == nil {
+		tags = append(tags, lang)
+	}
+	return append(tags, ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+}
+
+// ParseAcceptLanguage разбирает "en-GB,en;q=0.9,ru;q=0.5" в ["en-gb", "en", "ru"].
+// Некорректные теги, "*" и q=0 пропускаются.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var list []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		normalized, err := Normalize(tag)
+		if err != nil || q <= 0 {
+			continue
+		}
+		list = append(list, weighted{tag: normalized, q: q})
+	}
+	// Стабильная сортировка сохраняет порядок тегов с одинаковым весом
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	tags := make([]string, len(list))
+	for i, w := range list {
+		tags[i] = w.tag
+	}
+	return tags
+}
@@ -0,0 +1,274 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency — валюта по умолчанию. В базе суммы лежат как DECIMAL(10, 2)
+// без кода валюты, поэтому всё, что читается из базы, считается в рублях.
+const DefaultCurrency = "RUB"
+
+// minorPerMajor — сколько копеек в рубле. Все поддерживаемые валюты имеют 2 знака после запятой.
+const minorPerMajor = 100
+
+var ErrInvalidAmount = errors.New("некорректная денежная сумма")
+
+// ErrUnsupportedCurrency — сумма пришла не в валюте сервиса. Арифметика разных валют паникует,
+// поэтому чужая валюта отсекается еще при разборе JSON, а не где-то в расчете заказа.
+var ErrUnsupportedCurrency = errors.New("поддерживается только валюта " + DefaultCurrency)
+
+// Money — точная денежная сумма: целое число минимальных единиц (копеек) плюс валюта.
+// Никаких float64: сложение и умножение на количество считаются без погрешности,
+// а округление происходит только в Parse, Percent и Fraction по одному правилу — "половина вверх".
+type Money struct {
+	Amount   int64  // в минимальных единицах (копейках)
+	Currency string // код ISO 4217
+}
+
+// New создает сумму из копеек
+func New(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// FromMinor создает сумму в валюте по умолчанию из копеек
+func FromMinor(minor int64) Money {
+	return New(minor, DefaultCurrency)
+}
+
+// FromMajor создает сумму в валюте по умолчанию из целых рублей
+func FromMajor(major int64) Money {
+	return FromMinor(major * minorPerMajor)
+}
+
+// Zero — нулевая сумма в валюте по умолчанию
+func Zero() Money {
+	return FromMinor(0)
+}
+
+// Parse разбирает десятичную строку ("350", "350.5", "-12.345") в рублях.
+// Лишние знаки после запятой округляются "половина вверх" (от нуля).
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	major, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || major > math.MaxInt64/minorPerMajor-1 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	// Дополняем дробную часть до 2 знаков, третий знак решает округление
+	padded := fracPart + "000"
+	minor, _ := strconv.ParseInt(padded[:2], 10, 64)
+	amount := major*minorPerMajor + minor
+	if padded[2] >= '5' {
+		amount++
+	}
+
+	if negative {
+		amount = -amount
+	}
+	return FromMinor(amount), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String возвращает сумму десятичной строкой без валюты: "350.00"
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorPerMajor, amount%minorPerMajor)
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Add складывает суммы. Складывать разные валюты нельзя — это ошибка программиста.
+func (m Money) Add(o Money) Money {
+	return New(m.Amount+o.Amount, m.sameCurrency(o))
+}
+
+func (m Money) Sub(o Money) Money {
+	return New(m.Amount-o.Amount, m.sameCurrency(o))
+}
+
+// Mul умножает цену на количество
+func (m Money) Mul(quantity int) Money {
+	return New(m.Amount*int64(quantity), m.currency())
+}
+
+// Percent возвращает percent% от суммы с округлением "половина вверх"
+func (m Money) Percent(percent int64) Money {
+	return New(divRound(m.Amount*percent, 100), m.currency())
+}
+
+// Fraction возвращает num/den от суммы с округлением "половина вверх": долю скидки, НДС внутри цены
+func (m Money) Fraction(num, den int64) Money {
+	return New(divRound(m.Amount*num, den), m.currency())
+}
+
+// Cmp сравнивает суммы: -1, 0 или 1
+func (m Money) Cmp(o Money) int {
+	m.sameCurrency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// divRound — целочисленное деление с округлением "половина вверх" (от нуля)
+func divRound(a, b int64) int64 {
+	q, r := a/b, a%b
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= b {
+		if a < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+func (m Money) sameCurrency(o Money) string {
+	if m.currency() != o.currency() {
+		panic(fmt.Sprintf("money: разные валюты %s и %s", m.currency(), o.currency()))
+	}
+	return m.currency()
+}
+
+// jsonMoney — формат в API: сумма строкой, чтобы клиенты не теряли копейки на float
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON кодирует сумму как {"amount": "350.00", "currency": "RUB"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.String(), Currency: m.currency()})
+}
+
+// UnmarshalJSON принимает основной формат {"amount": "350.00", "currency": "RUB"},
+// а для совместимости со старыми клиентами — просто число 350.5 или строку "350.50" в рублях.
+// Валюта, отличная от DefaultCurrency, — ошибка ErrUnsupportedCurrency; null оставляет значение как есть.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return ErrInvalidAmount
+	}
+	if string(data) == "null" {
+		return nil
+	}
+
+	switch data[0] {
+	case '{':
+		var v jsonMoney
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		parsed, err := Parse(v.Amount)
+		if err != nil {
+			return err
+		}
+		if v.Currency != "" && !strings.EqualFold(v.Currency, DefaultCurrency) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCurrency, v.Currency)
+		}
+		*m = parsed
+		return nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		// Число разбираем как текст, не проходя через float64
+		parsed, err := Parse(string(data))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+}
+
+// Value отдает сумму в базу десятичной строкой — Postgres сам приведет её к DECIMAL
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan читает DECIMAL из базы (pgx отдает его строкой)
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case []byte:
+		return m.Scan(string(v))
+	case int64:
+		*m = FromMajor(v)
+	case float64:
+		return m.Scan(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		return fmt.Errorf("%w: NULL", ErrInvalidAmount)
+	default:
+		return fmt.Errorf("%w: неподдерживаемый тип %T", ErrInvalidAmount, src)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"350", 35000},
+		{"350.5", 35050},
+		{"350.50", 35050},
+		{" 12.3 ", 1230},
+		{".5", 50},
+		{"+1.01", 101},
+		{"-12.34", -1234},
+		{"0.004", 0},
+		{"0.005", 1},   // половина вверх
+		{"1.994", 199}, // третий знак меньше 5 — отбрасываем
+		{"1.995", 200},
+		{"-12.345", -1235}, // от нуля
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.in, err)
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Fatalf("Parse(%q) = %+v, ожидали %d %s", tt.in, got, tt.want, DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "  ", "abc", "1,5", "1.2.3", "--1", "1e3", "99999999999999999999"} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q) = %v, ожидали ErrInvalidAmount", in, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{35000, "350.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{-1234, "-12.34"},
+		{0, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := FromMinor(tt.amount).String(); got != tt.want {
+			t.Errorf("FromMinor(%d).String() = %q, ожидали %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := FromMinor(1050), FromMinor(250)
+	if got := a.Add(b); got.Amount != 1300 {
+		t.Errorf("Add = %v", got)
+	}
+	if got := b.Sub(a); got.Amount != -800 || !got.IsNegative() {
+		t.Errorf("Sub = %v", got)
+	}
+	if got := a.Mul(3); got.Amount != 3150 {
+		t.Errorf("Mul = %v", got)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(FromMinor(1050)) != 0 {
+		t.Error("Cmp сравнивает неверно")
+	}
+	// Пустая валюта — это валюта по умолчанию
+	if got := New(100, "").Add(FromMinor(1)); got.Currency != DefaultCurrency {
+		t.Errorf("Add с пустой валютой = %+v", got)
+	}
+	if !Zero().IsZero() || FromMajor(2).Amount != 200 {
+		t.Error("Zero/FromMajor")
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want int64
+	}{
+		{"10% от 123.45", FromMinor(12345).Percent(10), 1235},
+		{"10% от 123.44", FromMinor(12344).Percent(10), 1234},
+		{"50% от 0.01", FromMinor(1).Percent(50), 1},
+		{"15% от -1.50", FromMinor(-150).Percent(15), -23},
+	}
+	for _, tt := range tests {
+		if tt.got.Amount != tt.want {
+			t.Errorf("%s = %d, ожидали %d", tt.name, tt.got.Amount, tt.want)
+		}
+	}
+}
+
+func TestFraction(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want int64
+	}{
+		{"1/3 от 1.00", FromMinor(100).Fraction(1, 3), 33},
+		{"2/3 от 1.00", FromMinor(100).Fraction(2, 3), 67},
+		{"НДС 20/120 от 120.00", FromMinor(12000).Fraction(20, 120), 2000},
+		{"НДС 10/110 от 99.99", FromMinor(9999).Fraction(10, 110), 909},
+		{"половина от -0.03", FromMinor(-3).Fraction(1, 2), -2},
+	}
+	for _, tt := range tests {
+		if tt.got.Amount != tt.want {
+			t.Errorf("%s = %d, ожидали %d", tt.name, tt.got.Amount, tt.want)
+		}
+	}
+}
+
+func TestCurrencyMismatchPanics(t *testing.T) {
+	ops := map[string]func(a, b Money){
+		"Add": func(a, b Money) { a.Add(b) },
+		"Sub": func(a, b Money) { a.Sub(b) },
+		"Cmp": func(a, b Money) { a.Cmp(b) },
+	}
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s с разными валютами должен паниковать", name)
+				}
+			}()
+			op(FromMinor(100), New(100, "USD"))
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(FromMinor(35050))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":"350.50","currency":"RUB"}` {
+		t.Fatalf("Marshal = %s", data)
+	}
+	// Пустая валюта в JSON отдается как валюта по умолчанию
+	if data, _ := json.Marshal(New(1, "")); string(data) != `{"amount":"0.01","currency":"RUB"}` {
+		t.Fatalf("Marshal без валюты = %s", data)
+	}
+
+	tests := []struct {
+		in       string
+		amount   int64
+		currency string
+	}{
+		{`{"amount":"350.50","currency":"RUB"}`, 35050, "RUB"},
+		{`{"amount":"10","currency":"rub"}`, 1000, "RUB"},
+		{`{"amount":"10"}`, 1000, "RUB"},
+		{`350.5`, 35050, "RUB"},
+		{`"350.50"`, 35050, "RUB"},
+		{`0.1`, 10, "RUB"},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Errorf("Unmarshal(%s) = %v", tt.in, err)
+			continue
+		}
+		if m.Amount != tt.amount || m.Currency != tt.currency {
+			t.Errorf("Unmarshal(%s) = %+v", tt.in, m)
+		}
+	}
+
+	for _, in := range []string{`"abc"`, `{"amount":"x"}`, `true`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) должен вернуть ошибку, получили %+v", in, m)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":"10","currency":"USD"}`), &m); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("Unmarshal(USD) = %v, ожидали ErrUnsupportedCurrency", err)
+	}
+
+	// null не трогает уже заполненное значение — как у встроенных типов
+	var p struct {
+		Price Money `json:"price"`
+	}
+	p.Price = FromMinor(500)
+	if err := json.Unmarshal([]byte(`{"price":null}`), &p); err != nil || p.Price != FromMinor(500) {
+		t.Errorf("Unmarshal(null) = %+v, %v", p.Price, err)
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	tests := []struct {
+		src  any
+		want int64
+	}{
+		{"350.50", 35050},
+		{[]byte("12.3"), 1230},
+		{int64(7), 700},
+		{float64(1.1), 110},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) = %v", tt.src, err)
+			continue
+		}
+		if m.Amount != tt.want || m.Currency != DefaultCurrency {
+			t.Errorf("Scan(%v) = %+v", tt.src, m)
+		}
+	}
+
+	for _, src := range []any{nil, "abc", true} {
+		var m Money
+		if err := m.Scan(src); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Scan(%v) = %v, ожидали ErrInvalidAmount", src, err)
+		}
+	}
+
+	v, err := FromMinor(-1234).Value()
+	if err != nil || v != "-12.34" {
+		t.Fatalf("Value() = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package outbox — transactional outbox для событий сервисов. Событие пишется в таблицу
+// outbox той же транзакцией, что и само изменение, а Relay публикует его в RabbitMQ
+// только после коммита. Доставка "как минимум один раз": потребители отбрасывают повторы по id.
+//
+// У каждого сервиса своя таблица и свой exchange (см. internal/catalog/events, internal/order/events);
+// схема таблицы одинаковая: id, event_type, version, payload, ordering_key, created_at, published_at
+// и поля повторов attempts, next_attempt_at, last_error.
+//
+// События с одинаковым ordering_key (например, одного заказа) публикуются строго в порядке id:
+// пока предыдущее не отправлено, следующее ждет, даже если его взял другой экземпляр relay.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Envelope — сообщение в шине
+type Envelope struct {
+	ID         int64           `json:"id"` // номер в outbox, растет монотонно
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Execer — транзакция, в которой произошло изменение (pgx.Tx)
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Enqueue записывает событие в таблицу table. Вызывать нужно в транзакции изменения:
+// откатится транзакция — пропадет и событие. Пустой orderingKey — порядок не важен.
+func Enqueue(ctx context.Context, tx Execer, table, orderingKey, eventType string, version int, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO "+table+" (event_type, version, payload, ordering_key) VALUES ($1, $2, $3, NULLIF($4, ''))",
+		eventType, version, payload, orderingKey)
+	return err
+}
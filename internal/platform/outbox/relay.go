@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	batchSize = 100
+	// retention — сколько хранить уже отправленные события (для разбора инцидентов)
+	retention       = 7 * 24 * time.Hour
+	cleanupInterval = time.Hour
+	// maxBackoff — самая долгая пауза перед повтором неотправленного события
+	maxBackoff = 5 * time.Minute
+)
+
+var errNotConfirmed = errors.New("RabbitMQ не подтвердил сообщение")
+
+// Relay переносит события из outbox в RabbitMQ. Строки берутся через FOR UPDATE SKIP LOCKED,
+// поэтому несколько экземпляров сервиса не отправят одно событие дважды одновременно.
+type Relay struct {
+	db       *pgxpool.Pool
+	url      string
+	interval time.Duration
+	table    string // таблица outbox сервиса
+	exchange string // topic-exchange, куда уходят ее события
+
+	conn        *amqp091.Connection
+	ch          *amqp091.Channel
+	lastCleanup time.Time
+}
+
+func NewRelay(db *pgxpool.Pool, url string, interval time.Duration, table, exchange string) *Relay {
+	return &Relay{db: db, url: url, interval: interval, table: table, exchange: exchange}
+}
+
+// Run работает до отмены ctx. Недоступность RabbitMQ не валит сервис:
+// события копятся в outbox и уходят после переподключения.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	defer r.close()
+
+	for {
+		if err := r.drain(ctx); err != nil {
+			log.Printf("outbox: публикация событий из %s: %v", r.table, err)
+			r.close() // на следующем тике подключимся заново
+		}
+		r.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain отправляет пачки, пока есть что отправлять. Из каждой очереди по ordering_key
+// в пачку попадает только голова, поэтому неполная пачка не значит, что outbox пуст.
+func (r *Relay) drain(ctx context.Context) error {
+	for {
+		n, err := r.publishBatch(ctx)
+		if err != nil || n == 0 {
+			return err
+		}
+	}
+}
+
+type pending struct {
+	Envelope
+	key      string
+	attempts int
+}
+
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Событие берем, только если перед ним в его очереди нет неотправленных
+	query := `
+		SELECT id, event_type, version, payload, created_at, COALESCE(ordering_key, ''), attempts
+		FROM ` + r.table + ` o
+		WHERE published_at IS NULL AND next_attempt_at <= NOW()
+			AND (ordering_key IS NULL OR NOT EXISTS (
+				SELECT 1 FROM ` + r.table + ` prev
+				WHERE prev.ordering_key = o.ordering_key AND prev.published_at IS NULL AND prev.id < o.id))
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(ctx, query, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pending, error) {
+		var e pending
+		err := row.Scan(&e.ID, &e.Type, &e.Version, &e.Data, &e.OccurredAt, &e.key, &e.attempts)
+		return e, err
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := make([]int64, 0, len(events))
+	publishErr := r.connect()
+	if publishErr == nil {
+		for _, e := range events {
+			if publishErr = r.publish(ctx, e); publishErr != nil {
+				// Не отправленное событие откладываем; остальные из пачки попробуем на следующем тике
+				if err := r.postpone(ctx, tx, e, publishErr); err != nil {
+					return 0, err
+				}
+				break
+			}
+			published = append(published, e.ID)
+		}
+	} else if err := r.postpone(ctx, tx, events[0], publishErr); err != nil {
+		return 0, err
+	}
+
+	// Отмечаем то, что успели отправить, даже если дальше случилась ошибка
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE "+r.table+" SET published_at = NOW() WHERE id = ANY($1)", published); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+// postpone откладывает событие с паузой 1, 2, 4... секунд, но не больше maxBackoff.
+// События с тем же ordering_key ждут вместе с ним.
+func (r *Relay) postpone(ctx context.Context, tx pgx.Tx, e pending, cause error) error {
+	delay := maxBackoff
+	if e.attempts < 9 {
+		delay = min(time.Second<<e.attempts, maxBackoff)
+	}
+	query := `
+		UPDATE ` + r.table + `
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+		WHERE id = $1`
+	_, err := tx.Exec(ctx, query, e.ID, cause.Error(), delay.Seconds())
+	return err
+}
+
+func (r *Relay) publish(ctx context.Context, e pending) error {
+	body, err := json.Marshal(e.Envelope)
+	if err != nil {
+		return err
+	}
+	confirm, err := r.ch.PublishWithDeferredConfirmWithContext(ctx, r.exchange, e.Type, false, false, amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		MessageId:    fmt.Sprint(e.ID),
+		Type:         e.Type,
+		Timestamp:    e.OccurredAt,
+		Headers:      headers(e),
+		Body:         body,
+	})
+	if err != nil {
+		return err
+	}
+	// Ждем подтверждения брокера: только после него событие считается отправленным
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("%w: событие %d", errNotConfirmed, e.ID)
+	}
+	return nil
+}
+
+func headers(e pending) amqp091.Table {
+	if e.key == "" {
+		return nil
+	}
+	return amqp091.Table{"ordering_key": e.key}
+}
+
+func (r *Relay) connect() error {
+	if r.ch != nil && !r.ch.IsClosed() {
+		return nil
+	}
+	r.close()
+
+	conn, err := amqp091.Dial(r.url)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := ch.ExchangeDeclare(r.exchange, "topic", true, false, false, false, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	r.conn, r.ch = conn, ch
+	return nil
+}
+
+func (r *Relay) close() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.conn, r.ch = nil, nil
+}
+
+// cleanup раз в час удаляет давно отправленные события, чтобы outbox не рос бесконечно
+func (r *Relay) cleanup(ctx context.Context) {
+	if time.Since(r.lastCleanup) < cleanupInterval {
+		return
+	}
+	r.lastCleanup = time.Now()
+	query := "DELETE FROM " + r.table + " WHERE published_at < NOW() - make_interval(secs => $1)"
+	if _, err := r.db.Exec(ctx, query, retention.Seconds()); err != nil {
+		log.Printf("outbox: очистка %s: %v", r.table, err)
+	}
+}
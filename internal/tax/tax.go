@@ -0,0 +1,52 @@
+// Package tax — налоговые категории товаров и расчет НДС. Цены в каталоге уже включают
+// НДС, поэтому налог выделяется из суммы: при ставке 20% это 20/120 от цены.
+package tax
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JuniorCrafter/fooddelivery/internal/platform/money"
+)
+
+var ErrUnknownCategory = errors.New("неизвестная налоговая категория")
+
+// Category — ставка НДС товара или услуги, как она печатается в чеке
+type Category string
+
+const (
+	VAT20 Category = "vat20" // основная ставка: готовая еда, напитки, доставка
+	VAT10 Category = "vat10" // льготная: часть продуктов питания по перечню Правительства РФ
+	VAT0  Category = "vat0"
+	NoVAT Category = "none" // без НДС, например у продавца на упрощенке
+
+	// Default — категория товара, для которого ее не указали
+	Default = VAT20
+	// Delivery — доставка облагается по основной ставке
+	Delivery = VAT20
+)
+
+var rates = map[Category]int64{VAT20: 20, VAT10: 10, VAT0: 0, NoVAT: 0}
+
+// Parse проверяет категорию из запроса; пустая строка — Default
+func Parse(s string) (Category, error) {
+	if s == "" {
+		return Default, nil
+	}
+	c := Category(s)
+	if _, ok := rates[c]; !ok {
+		return "", fmt.Errorf("%w: %q, допустимы vat20, vat10, vat0, none", ErrUnknownCategory, s)
+	}
+	return c, nil
+}
+
+// Rate — ставка в процентах
+func (c Category) Rate() int64 {
+	return rates[c]
+}
+
+// Included — НДС, который уже входит в сумму gross, с округлением "половина вверх"
+func (c Category) Included(gross money.Money) money.Money {
+	rate := c.Rate()
+	return gross.Fraction(rate, 100+rate)
+}